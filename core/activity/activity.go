@@ -0,0 +1,205 @@
+// Package activity provides a log of the signals that are detected on the band.
+package activity
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ftl/panacotta/core"
+)
+
+// Format of the activity log.
+type Format string
+
+// All supported formats.
+const (
+	// FormatCSV is a CSV file with a header line.
+	FormatCSV Format = "csv"
+	// FormatJSONL is a file with one JSON object per line.
+	FormatJSONL Format = "jsonl"
+)
+
+// ParseFormat parses the given string into a format.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatCSV, FormatJSONL:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown activity log format %q", s)
+	}
+}
+
+const (
+	logBufferSize = 64
+	dayLayout     = "20060102"
+)
+
+var csvHeader = []string{"first_seen", "last_seen", "frequency", "bandwidth", "max_level", "band", "class"}
+
+// entry is the representation of a signal in the log.
+type entry struct {
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	Frequency float64   `json:"frequency"`
+	Bandwidth float64   `json:"bandwidth"`
+	MaxLevel  float64   `json:"max_level"`
+	Band      string    `json:"band"`
+	Class     string    `json:"class"`
+}
+
+func toEntry(activity core.SignalActivity) entry {
+	return entry{
+		FirstSeen: activity.FirstSeen.UTC(),
+		LastSeen:  activity.LastSeen.UTC(),
+		Frequency: float64(activity.Frequency),
+		Bandwidth: float64(activity.Bandwidth),
+		MaxLevel:  float64(activity.MaxLevel),
+		Band:      string(activity.Band),
+		Class:     activity.Class.String(),
+	}
+}
+
+func (e entry) csvRecord() []string {
+	return []string{
+		e.FirstSeen.Format(time.RFC3339),
+		e.LastSeen.Format(time.RFC3339),
+		fmt.Sprintf("%.0f", e.Frequency),
+		fmt.Sprintf("%.0f", e.Bandwidth),
+		fmt.Sprintf("%.1f", e.MaxLevel),
+		e.Band,
+		e.Class,
+	}
+}
+
+// NewLogger returns a new logger that writes the signal activity into the given directory. There is one file per
+// day (UTC), named after the day the signals were last seen.
+func NewLogger(directory string, format Format) *Logger {
+	return &Logger{
+		directory: directory,
+		format:    format,
+		entries:   make(chan core.SignalActivity, logBufferSize),
+		done:      make(chan struct{}),
+	}
+}
+
+// Logger writes the signal activity asynchronously into daily files.
+type Logger struct {
+	directory string
+	format    Format
+	entries   chan core.SignalActivity
+	done      chan struct{}
+
+	day  string
+	file *os.File
+}
+
+// Run the logger until it is closed.
+func (l *Logger) Run() {
+	defer log.Print("activity log shutdown")
+	defer close(l.done)
+	for activity := range l.entries {
+		err := l.write(activity)
+		if err != nil {
+			log.Printf("Cannot write the activity log: %v", err)
+		}
+	}
+	err := l.close()
+	if err != nil {
+		log.Printf("Cannot close the activity log: %v", err)
+	}
+}
+
+// Close the logger and wait until all pending entries are written. Log must not be called afterwards.
+func (l *Logger) Close() {
+	close(l.entries)
+	<-l.done
+}
+
+// Log the given signal activity.
+func (l *Logger) Log(activity core.SignalActivity) {
+	select {
+	case l.entries <- activity:
+	default:
+		log.Print("activity log hangs")
+	}
+}
+
+// Filename returns the name of the log file for the given day.
+func (l *Logger) Filename(day time.Time) string {
+	return filepath.Join(l.directory, fmt.Sprintf("panacotta_activity_%s.%s", day.UTC().Format(dayLayout), l.format))
+}
+
+func (l *Logger) write(activity core.SignalActivity) error {
+	err := l.rotate(activity.LastSeen)
+	if err != nil {
+		return err
+	}
+
+	e := toEntry(activity)
+	switch l.format {
+	case FormatJSONL:
+		return json.NewEncoder(l.file).Encode(e)
+	default:
+		return l.writeCSV(e.csvRecord())
+	}
+}
+
+func (l *Logger) writeCSV(record []string) error {
+	writer := csv.NewWriter(l.file)
+	err := writer.Write(record)
+	if err != nil {
+		return err
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// rotate opens the file of the given day, if it is not already open. New CSV files start with a header line.
+func (l *Logger) rotate(t time.Time) error {
+	day := t.UTC().Format(dayLayout)
+	if day == l.day && l.file != nil {
+		return nil
+	}
+	err := l.close()
+	if err != nil {
+		return err
+	}
+
+	filename := l.Filename(t)
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.Wrap(err, "cannot open activity log")
+	}
+	l.file = file
+	l.day = day
+
+	if l.format != FormatCSV {
+		return nil
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return errors.Wrapf(err, "cannot access activity log %s", filename)
+	}
+	if info.Size() > 0 {
+		return nil
+	}
+	return l.writeCSV(csvHeader)
+}
+
+func (l *Logger) close() error {
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	l.day = ""
+	return err
+}
@@ -0,0 +1,127 @@
+package activity
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ftl/hamradio/bandplan"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ftl/panacotta/core"
+)
+
+func TestParseFormat(t *testing.T) {
+	format, err := ParseFormat("JSONL")
+	assert.NoError(t, err)
+	assert.Equal(t, FormatJSONL, format)
+
+	_, err = ParseFormat("xml")
+	assert.Error(t, err)
+}
+
+func TestCSVRotatesDaily(t *testing.T) {
+	dir, err := ioutil.TempDir("", "panacotta-activity")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	saturday := time.Date(2020, 6, 6, 23, 59, 0, 0, time.UTC)
+	sunday := saturday.Add(2 * time.Minute)
+	logger := NewLogger(dir, FormatCSV)
+	require.NoError(t, logger.write(testActivity(saturday, 7010000)))
+	require.NoError(t, logger.write(testActivity(saturday, 7012000)))
+	require.NoError(t, logger.write(testActivity(sunday, 7010000)))
+	require.NoError(t, logger.close())
+
+	saturdayLog, err := ioutil.ReadFile(logger.Filename(saturday))
+	require.NoError(t, err)
+	sundayLog, err := ioutil.ReadFile(logger.Filename(sunday))
+	require.NoError(t, err)
+
+	assert.Equal(t, `first_seen,last_seen,frequency,bandwidth,max_level,band,class
+2020-06-06T23:58:30Z,2020-06-06T23:59:00Z,7010000,80,-73.0,40m,CW
+2020-06-06T23:58:30Z,2020-06-06T23:59:00Z,7012000,80,-73.0,40m,CW
+`, string(saturdayLog))
+	assert.Equal(t, 2, strings.Count(string(sundayLog), "\n"))
+}
+
+func TestCSVAppendsWithoutHeader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "panacotta-activity")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	now := time.Date(2020, 6, 6, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 2; i++ {
+		logger := NewLogger(dir, FormatCSV)
+		require.NoError(t, logger.write(testActivity(now, 7010000)))
+		require.NoError(t, logger.close())
+	}
+
+	content, err := ioutil.ReadFile(NewLogger(dir, FormatCSV).Filename(now))
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(content), "first_seen"))
+	assert.Equal(t, 3, strings.Count(string(content), "\n"))
+}
+
+func TestCloseWritesPendingEntries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "panacotta-activity")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	now := time.Date(2020, 6, 6, 12, 0, 0, 0, time.UTC)
+	logger := NewLogger(dir, FormatCSV)
+	logger.Log(testActivity(now, 7010000))
+	logger.Log(testActivity(now, 7012000))
+	go logger.Run()
+	logger.Close()
+
+	content, err := ioutil.ReadFile(logger.Filename(now))
+	require.NoError(t, err)
+	assert.Equal(t, 3, strings.Count(string(content), "\n"))
+}
+
+func TestJSONL(t *testing.T) {
+	dir, err := ioutil.TempDir("", "panacotta-activity")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	now := time.Date(2020, 6, 6, 12, 0, 0, 0, time.UTC)
+	logger := NewLogger(dir, FormatJSONL)
+	require.NoError(t, logger.write(testActivity(now, 7010000)))
+	require.NoError(t, logger.write(testActivity(now, 7012000)))
+	require.NoError(t, logger.close())
+
+	file, err := os.Open(logger.Filename(now))
+	require.NoError(t, err)
+	defer file.Close()
+
+	var entries []entry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var e entry
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &e))
+		entries = append(entries, e)
+	}
+	if assert.Equal(t, 2, len(entries)) {
+		assert.Equal(t, 7012000.0, entries[1].Frequency)
+		assert.Equal(t, "40m", entries[1].Band)
+		assert.True(t, now.Equal(entries[1].LastSeen))
+	}
+}
+
+func testActivity(lastSeen time.Time, frequency core.Frequency) core.SignalActivity {
+	return core.SignalActivity{
+		FirstSeen: lastSeen.Add(-30 * time.Second),
+		LastSeen:  lastSeen,
+		Frequency: frequency,
+		Bandwidth: 80,
+		MaxLevel:  -73,
+		Band:      bandplan.Band40m,
+		Class:     core.SignalCW,
+	}
+}
@@ -0,0 +1,31 @@
+package app
+
+import (
+	"log"
+
+	"github.com/ftl/hamradio/cfg"
+
+	"github.com/ftl/panacotta/core/activity"
+	"github.com/ftl/panacotta/core/panorama"
+)
+
+// startActivityLog records the signals that are detected in the given panorama into daily files in the configuration
+// directory. The main loop closes the log when it stops.
+func (c *Controller) startActivityLog(p *panorama.Panorama) {
+	format, err := activity.ParseFormat(c.config.ActivityLogFormat)
+	if err != nil {
+		log.Printf("Cannot start the activity log: %v", err)
+		return
+	}
+	directory, err := cfg.PrepareDirectory("")
+	if err != nil {
+		log.Printf("Cannot start the activity log: %v", err)
+		return
+	}
+
+	logger := activity.NewLogger(directory, format)
+	go logger.Run()
+	p.SetActivityLog(logger)
+	c.mainLoop.activityLog = logger
+	log.Printf("Activity log in %s", directory)
+}
@@ -4,9 +4,13 @@ import (
 	"log"
 
 	"github.com/ftl/panacotta/core"
+	"github.com/ftl/panacotta/core/cw"
 	"github.com/ftl/panacotta/core/dsp"
+	"github.com/ftl/panacotta/core/iaru"
+	"github.com/ftl/panacotta/core/iq"
 	"github.com/ftl/panacotta/core/panorama"
 	"github.com/ftl/panacotta/core/rtlsdr"
+	"github.com/ftl/panacotta/core/rtltcp"
 	"github.com/ftl/panacotta/core/vfo"
 )
 
@@ -29,17 +33,16 @@ type Controller struct {
 
 // Startup the application.
 func (c *Controller) Startup() {
-	// configuration
-	ifCenter := 67899000  // this is fix for the FT-450D and specific to our method
-	sampleRate := 1800000 // 2097152 // 1800000 // this is specific to our method
-	blockSize := 65536    // 32768    // 131072    // this is the number of *complex* samples in one block
 	c.fullRangeMode = false
+	if c.config.Testmode && c.config.Testfile != "" {
+		c.useRecordingParameters(c.config.Testfile)
+	}
 
-	rxCenter := ifCenter - (sampleRate / 4)
-	log.Printf("RX @ %v %d ppm", rxCenter, c.config.FrequencyCorrection)
+	rxCenter := c.config.RXCenter()
+	log.Printf("Rig %s: IF @ %v, RX @ %v %d ppm", c.config.Rig, c.config.IFCenter, rxCenter, c.config.FrequencyCorrection)
 	log.Printf("FFT per second: %d", c.config.FFTPerSecond)
 
-	samplesInput, err := c.openSamplesInput(rxCenter, sampleRate, blockSize, c.config.FrequencyCorrection, c.config.Testmode)
+	samplesInput, err := c.openSamplesInput(int(rxCenter), c.config.SampleRate, c.config.BlockSize, c.config.FrequencyCorrection)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -47,6 +50,9 @@ func (c *Controller) Startup() {
 		<-c.stop
 		samplesInput.Close()
 	}()
+	if c.config.RTLTCPServer != "" {
+		c.shareSamples(samplesInput)
+	}
 
 	vfo, err := vfo.Open(c.config.VFOHost)
 	if err != nil {
@@ -59,34 +65,133 @@ func (c *Controller) Startup() {
 		p *panorama.Panorama
 	)
 	if c.fullRangeMode {
-		d = dsp.NewFullRange(sampleRate, core.Frequency(ifCenter), core.Frequency(-sampleRate/4))
+		d = dsp.NewFullRange(c.config.SampleRate, c.config.IFCenter, c.config.EffectiveRXOffset())
 		p = panorama.NewFullSpectrum(0, core.FrequencyRange{}, 0)
 	} else {
-		d = dsp.New(sampleRate, core.Frequency(ifCenter), core.Frequency(-sampleRate/4))
+		d = dsp.New(c.config.SampleRate, c.config.IFCenter, c.config.EffectiveRXOffset())
 		p = panorama.New(0, core.FrequencyRange{}, 0)
 	}
 	p.SetDynamicRange(c.config.DynamicRange)
+	plan, err := iaru.Bandplan(c.config.IARURegion)
+	if err != nil {
+		log.Printf("Using the bandplan of IARU region 1: %v", err)
+	} else {
+		p.SetBandplan(plan)
+	}
+	p.SetSegmentWarning(c.config.SegmentWarning)
+	p.SetFreezeOnTX(c.config.FreezeOnTX)
+	d.SetWindow(c.config.FFTWindow)
+	d.SetAveraging(c.config.Averaging)
+	d.SetOverlap(c.config.FFTOverlap, c.config.FFTPerSecond)
+	d.SetWorkers(c.config.DSPWorkers)
+	d.SetPeakDetection(c.config.PeakDetection)
+	p.SetPeakPersistence(c.config.PeakDetection.Persistence)
+	p.SetSpotMaxAge(c.config.SpotMaxAge)
 	go d.Run(c.stop)
 
 	c.mainLoop = newMainLoop(samplesInput, d, vfo, p, c.config.FFTPerSecond)
+	c.mainLoop.correction = dsp.NewCorrection(c.config.IQCorrection)
+	c.mainLoop.fftWindow = c.config.FFTWindow
+	c.mainLoop.averaging = c.config.Averaging
+	c.mainLoop.streaming = c.config.FFTOverlap > 0
+	c.mainLoop.freezeOnTX = c.config.FreezeOnTX
+	c.mainLoop.peakDetection = c.config.PeakDetection
+	c.mainLoop.defaultPeakDetection = c.config.PeakDetection
+	c.mainLoop.peakPresets = c.config.PeakPresets
+	if c.config.ActivityLog {
+		c.startActivityLog(p)
+	}
+	if c.config.CWSkimmer {
+		skimmer := cw.NewSkimmer(c.config.SampleRate, c.config.IFCenter, c.config.EffectiveRXOffset())
+		go skimmer.Run(c.stop)
+		c.mainLoop.skimmer = skimmer
+	}
+	c.loadBookmarks(p)
+	if c.config.ClusterAddress != "" {
+		c.mainLoop.spots = c.startCluster(c.config.ClusterAddress)
+	}
+	if c.config.RBNAddress != "" {
+		c.mainLoop.rbnSpots = c.startCluster(c.config.RBNAddress)
+	}
 	go c.mainLoop.Run(c.stop)
 }
 
-func (c *Controller) openSamplesInput(centerFrequency int, sampleRate int, blockSize int, frequencyCorrection int, testmode bool) (core.SamplesInput, error) {
-	if testmode {
+func (c *Controller) openSamplesInput(centerFrequency int, sampleRate int, blockSize int, frequencyCorrection int) (core.SamplesInput, error) {
+	if c.config.Testmode && c.config.Testfile != "" {
+		log.Printf("Testmode, replaying %s", c.config.Testfile)
+		input, err := dsp.NewFileInput(c.config.Testfile, blockSize, sampleRate, c.config.TestfileLoop)
+		if err != nil {
+			return nil, err
+		}
+		c.config.SampleRate = input.SampleRate()
+		return input, nil
+	}
+	if c.config.Testmode {
 		log.Printf("Testmode, using random samples input")
 		return dsp.NewRandomInput(blockSize, sampleRate), nil
 		// return dsp.NewToneInput(blockSize, sampleRate, 460000.0), nil
+		// return cw.NewKeyedToneInput(blockSize, sampleRate, 460000.0, 25, "CQ TEST DL1ABC"), nil
 		// return dsp.NewSweepInput(blockSize, sampleRate, -float64(sampleRate/2), float64(sampleRate/2), float64(sampleRate)*0.001), nil
 		// return dsp.NewSweepInput(blockSize, sampleRate, 0, float64(sampleRate), float64(sampleRate)*0.001), nil
 	}
-	return rtlsdr.Open(centerFrequency, sampleRate, blockSize, frequencyCorrection)
+	if c.config.RTLTCPHost != "" {
+		log.Printf("Using rtl_tcp at %s", c.config.RTLTCPHost)
+		return rtltcp.Dial(c.config.RTLTCPHost, centerFrequency, sampleRate, blockSize, frequencyCorrection, c.config.Tuner)
+	}
+	device := rtlsdr.Device{Index: c.config.DeviceIndex, Serial: c.config.DeviceSerial}
+	return rtlsdr.Open(device, centerFrequency, sampleRate, blockSize, frequencyCorrection, c.config.Tuner)
 }
 
-// Shutdown the application.
+// shareSamples provides the raw samples of the local dongle to other applications through an rtl_tcp server.
+func (c *Controller) shareSamples(samplesInput core.SamplesInput) {
+	dongle, ok := samplesInput.(*rtlsdr.Dongle)
+	if !ok {
+		log.Print("The rtl_tcp server is only available with a local RTL-SDR dongle")
+		return
+	}
+
+	gains, err := dongle.TunerGains()
+	if err != nil {
+		log.Printf("Cannot read the tuner gains: %v", err)
+	}
+	info := rtltcp.DongleInfo{
+		TunerType: rtltcp.TunerTypeByName(dongle.TunerType()),
+		GainCount: len(gains),
+	}
+	server, err := rtltcp.Listen(c.config.RTLTCPServer, info)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	dongle.SetRawDataSink(server.Write)
+	go server.Run(c.stop)
+}
+
+// useRecordingParameters takes the sample rate and the IF parameters from the metadata of the given recording, so that
+// the replayed samples are interpreted the same way as they were recorded.
+func (c *Controller) useRecordingParameters(filename string) {
+	metadata, err := iq.ReadMetadata(filename)
+	if err != nil {
+		log.Printf("No metadata for %s, using the configured parameters: %v", filename, err)
+		return
+	}
+	if metadata.SampleRate > 0 {
+		c.config.SampleRate = metadata.SampleRate
+	}
+	if metadata.IFCenter > 0 {
+		c.config.IFCenter = metadata.IFCenter
+		c.config.RXOffset = metadata.RXCenter - metadata.IFCenter
+		c.config.FixedRXOffset = true
+	}
+}
+
+// Shutdown the application and wait until the main loop has finished its shutdown.
 func (c *Controller) Shutdown() {
 	defer log.Print("core.app shutdown")
 	close(c.stop)
+	if c.mainLoop != nil {
+		<-c.mainLoop.stopped
+	}
 }
 
 // Done indicate done.
@@ -0,0 +1,100 @@
+package app
+
+import (
+	"log"
+
+	"github.com/ftl/hamradio/cfg"
+
+	"github.com/ftl/panacotta/core"
+	"github.com/ftl/panacotta/core/bookmark"
+	"github.com/ftl/panacotta/core/panorama"
+)
+
+// bookmarkTolerance is the maximum distance between the VFO and a bookmark that is deleted.
+const bookmarkTolerance = core.Frequency(500)
+
+// loadBookmarks from the configuration directory and show them in the given panorama.
+func (c *Controller) loadBookmarks(p *panorama.Panorama) {
+	directory, err := cfg.PrepareDirectory("")
+	if err != nil {
+		log.Printf("Cannot load the bookmarks: %v", err)
+		return
+	}
+	store := bookmark.NewStore(directory)
+	err = store.Load()
+	if err != nil {
+		log.Printf("Cannot load the bookmarks: %v", err)
+		return
+	}
+	p.SetBookmarks(store.All())
+	c.mainLoop.bookmarks = store
+}
+
+// AddBookmark at the current VFO frequency.
+func (m *mainLoop) AddBookmark() {
+	m.q(func() {
+		if m.bookmarks == nil {
+			return
+		}
+		vfo, _ := m.panorama.VFO()
+		added := m.bookmarks.Add(core.Bookmark{Frequency: vfo.Frequency, Mode: vfo.Mode})
+		log.Printf("Bookmark %s @ %v", added.Label, added.Frequency)
+		m.bookmarksChanged()
+	})
+}
+
+// DeleteBookmark at the current VFO frequency.
+func (m *mainLoop) DeleteBookmark() {
+	m.q(func() {
+		if m.bookmarks == nil {
+			return
+		}
+		vfo, _ := m.panorama.VFO()
+		deleted, ok := m.bookmarks.Delete(vfo.Frequency, bookmarkTolerance)
+		if !ok {
+			return
+		}
+		log.Printf("Deleted bookmark %s @ %v", deleted.Label, deleted.Frequency)
+		m.bookmarksChanged()
+	})
+}
+
+// NextBookmark tunes the VFO to the next bookmark above the current VFO frequency.
+func (m *mainLoop) NextBookmark() {
+	m.q(func() {
+		if m.bookmarks == nil {
+			return
+		}
+		vfo, _ := m.panorama.VFO()
+		m.tuneToBookmark(m.bookmarks.Next(vfo.Frequency))
+	})
+}
+
+// PreviousBookmark tunes the VFO to the next bookmark below the current VFO frequency.
+func (m *mainLoop) PreviousBookmark() {
+	m.q(func() {
+		if m.bookmarks == nil {
+			return
+		}
+		vfo, _ := m.panorama.VFO()
+		m.tuneToBookmark(m.bookmarks.Previous(vfo.Frequency))
+	})
+}
+
+func (m *mainLoop) tuneToBookmark(bookmark core.Bookmark, ok bool) {
+	if !ok {
+		return
+	}
+	m.vfo.TuneTo(bookmark.Frequency)
+	if bookmark.Mode != "" {
+		m.vfo.SetMode(bookmark.Mode)
+	}
+}
+
+func (m *mainLoop) bookmarksChanged() {
+	m.panorama.SetBookmarks(m.bookmarks.All())
+	err := m.bookmarks.Save()
+	if err != nil {
+		log.Printf("Cannot save the bookmarks: %v", err)
+	}
+}
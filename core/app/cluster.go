@@ -0,0 +1,27 @@
+package app
+
+import (
+	"log"
+
+	"github.com/ftl/panacotta/core"
+	"github.com/ftl/panacotta/core/cluster"
+)
+
+// startCluster connects to the DX cluster or the RBN node at the given address and returns the channel of the received
+// spots. The channel is nil if the connection fails.
+func (c *Controller) startCluster(address string) <-chan core.Spot {
+	if c.config.ClusterCallsign == "" {
+		log.Printf("Cannot connect to %s: no callsign configured", address)
+		return nil
+	}
+	client, err := cluster.Dial(address, c.config.ClusterCallsign)
+	if err != nil {
+		log.Printf("Cannot connect to %s: %v", address, err)
+		return nil
+	}
+	go func() {
+		<-c.stop
+		client.Close()
+	}()
+	return client.Spots()
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/ftl/hamradio/bandplan"
 	"github.com/ftl/panacotta/core"
+	"github.com/ftl/panacotta/core/cw"
 )
 
 func newMainLoop(samplesInput core.SamplesInput, dsp dspType, vfo vfoType, panorama panoramaType, fftPerSecond int) *mainLoop {
@@ -21,9 +22,19 @@ func newMainLoop(samplesInput core.SamplesInput, dsp dspType, vfo vfoType, panor
 		redrawTick:     time.NewTicker(redrawInterval),
 		needFFTData:    true,
 		command:        make(chan command, 1),
+		stopped:        make(chan struct{}),
 
 		panoramaData: make(chan core.Panorama, 1),
 	}
+	if releaser, ok := samplesInput.(core.BlockReleaser); ok {
+		result.releaser = releaser
+	}
+	if meter, ok := samplesInput.(core.ClippingMeter); ok {
+		result.clippingMeter = meter
+	}
+	if reporter, ok := samplesInput.(core.InputStateReporter); ok {
+		result.inputState = reporter.InputState()
+	}
 
 	return result
 }
@@ -31,29 +42,85 @@ func newMainLoop(samplesInput core.SamplesInput, dsp dspType, vfo vfoType, panor
 type command func()
 
 type mainLoop struct {
-	samplesInput core.SamplesInput
-	dsp          dspType
-	vfo          vfoType
-	tuner        tuner
-	panorama     panoramaType
+	samplesInput  core.SamplesInput
+	releaser      core.BlockReleaser
+	clippingMeter core.ClippingMeter
+	inputState    <-chan core.InputState
+	correction    correctionType
+	dsp           dspType
+	vfo           vfoType
+	tuner         tuner
+	panorama      panoramaType
+	recorder      recorderType
+	activityLog   activityLogType
+	skimmer       skimmerType
+	spots         <-chan core.Spot
+	rbnSpots      <-chan core.Spot
+	bookmarks     bookmarkStore
+	fftWindow     core.FFTWindow
+	averaging     core.Averaging
+
+	peakDetection        core.PeakDetection
+	defaultPeakDetection core.PeakDetection
+	peakPresets          map[bandplan.BandName]core.PeakDetection
+	band                 bandplan.BandName
 
 	redrawInterval time.Duration
 	redrawTick     *time.Ticker
 	needFFTData    bool
+	streaming      bool // all samples are processed by the DSP, not only the ones needed for the next redraw
+	freezeOnTX     bool // no samples are processed while the transceiver is transmitting
+	transmitting   bool
 	command        chan command
+	stopped        chan struct{} // closed when the main loop has finished its shutdown
 
 	panoramaData chan core.Panorama
 }
 
+type correctionType interface {
+	Process(samples []complex128)
+}
+
 type dspType interface {
-	ProcessSamples(samples []complex128, fftRange core.FrequencyRange, vfo core.VFO)
+	ProcessSamples(samples []complex128, clipping float64, fftRange core.FrequencyRange, vfo core.VFO)
 	FFT() chan core.FFT
+	SetWindow(core.FFTWindow)
+	SetAveraging(core.Averaging)
+	SetPeakDetection(core.PeakDetection)
+	ResetHold()
+	ResetAveraging()
 }
 
 type vfoType interface {
 	Data() <-chan core.VFO
 	TuneBy(Δf core.Frequency)
 	TuneTo(f core.Frequency)
+	SetMode(mode string)
+	SetTXFrequency(f core.Frequency)
+}
+
+type recorderType interface {
+	Write([]complex128)
+	Close() error
+}
+
+type activityLogType interface {
+	Close()
+}
+
+type skimmerType interface {
+	Write([]complex128)
+	SetCarriers(core.VFO, []core.Frequency)
+	Texts() <-chan core.CWText
+}
+
+type bookmarkStore interface {
+	All() []core.Bookmark
+	Add(core.Bookmark) core.Bookmark
+	Delete(f core.Frequency, tolerance core.Frequency) (core.Bookmark, bool)
+	Next(core.Frequency) (core.Bookmark, bool)
+	Previous(core.Frequency) (core.Bookmark, bool)
+	Save() error
 }
 
 type panoramaType interface {
@@ -62,6 +129,13 @@ type panoramaType interface {
 	SetSize(core.Px, core.Px)
 	SetFFT(core.FFT)
 	SetVFO(core.VFO)
+	SetInputState(core.InputState)
+	SetPeakPersistence(time.Duration)
+	SetCWText(core.CWText)
+	SetSpot(core.Spot)
+	SetSkimmerSpot(core.Spot)
+	SetBookmarks([]core.Bookmark)
+	FlushActivityLog()
 	Data() core.Panorama
 	ToggleSignalDetection()
 	SignalDetectionActive() bool
@@ -79,19 +153,42 @@ type panoramaType interface {
 
 func (m *mainLoop) Run(stop chan struct{}) {
 	defer log.Print("main loop shutdown")
+	defer close(m.stopped)
+	var cwTexts <-chan core.CWText
+	if m.skimmer != nil {
+		cwTexts = m.skimmer.Texts()
+	}
 	for {
 		select {
 		case samples := <-m.samplesInput.Samples():
-			if !m.needFFTData {
-				continue
+			m.processSamples(samples)
+			if m.releaser != nil {
+				m.releaser.Release(samples)
 			}
-
-			vfo, _ := m.panorama.VFO()
-			frequencyRange := m.panorama.FrequencyRange()
-			m.dsp.ProcessSamples(samples, frequencyRange, vfo)
-			m.needFFTData = false
+		case state := <-m.inputState:
+			m.panorama.SetInputState(state)
 		case fft := <-m.dsp.FFT():
 			m.panorama.SetFFT(fft)
+			if m.skimmer != nil {
+				vfo, _ := m.panorama.VFO()
+				if !vfo.PTT {
+					m.skimmer.SetCarriers(vfo, cw.Carriers(fft))
+				}
+			}
+		case text := <-cwTexts:
+			m.panorama.SetCWText(text)
+		case spot, ok := <-m.spots:
+			if ok {
+				m.addSpot(spot)
+			} else {
+				m.spots = nil
+			}
+		case spot, ok := <-m.rbnSpots:
+			if ok {
+				m.addSpot(spot)
+			} else {
+				m.rbnSpots = nil
+			}
 		case <-m.redrawTick.C:
 			select {
 			case m.panoramaData <- m.panorama.Data():
@@ -101,15 +198,69 @@ func (m *mainLoop) Run(stop chan struct{}) {
 			}
 		case vfo := <-m.vfo.Data():
 			m.panorama.SetVFO(vfo)
+			m.updateBand()
+			m.updatePTT(vfo.PTT)
 		case command := <-m.command:
 			command()
 		case <-stop:
 			m.redrawTick.Stop()
+			m.stopRecording()
+			m.stopActivityLog()
 			return
 		}
 	}
 }
 
+func (m *mainLoop) processSamples(samples []complex128) {
+	if m.recorder != nil {
+		m.recorder.Write(samples)
+	}
+	if m.freezeOnTX && m.transmitting {
+		return
+	}
+	needDSP := m.needFFTData || m.streaming
+	if !needDSP && m.skimmer == nil {
+		return
+	}
+
+	if m.correction != nil {
+		m.correction.Process(samples)
+	}
+	if m.skimmer != nil {
+		m.skimmer.Write(samples)
+	}
+	if !needDSP {
+		return
+	}
+
+	var clipping float64
+	if m.clippingMeter != nil {
+		clipping = m.clippingMeter.Clipping(samples)
+	}
+	vfo, _ := m.panorama.VFO()
+	frequencyRange := m.panorama.FrequencyRange()
+	m.dsp.ProcessSamples(samples, clipping, frequencyRange, vfo)
+	m.needFFTData = false
+}
+
+// updatePTT tracks the transmit state of the transceiver. If the spectrum is frozen while transmitting, the averaged
+// spectrum is started over when the transmission ends, so that it contains no samples from before the transmission.
+func (m *mainLoop) updatePTT(ptt bool) {
+	if m.freezeOnTX && m.transmitting && !ptt {
+		m.dsp.ResetAveraging()
+	}
+	m.transmitting = ptt
+}
+
+// addSpot shows the spots of skimmers separately from the spots of humans.
+func (m *mainLoop) addSpot(spot core.Spot) {
+	if spot.Skimmer {
+		m.panorama.SetSkimmerSpot(spot)
+	} else {
+		m.panorama.SetSpot(spot)
+	}
+}
+
 // Panorama data for drawing
 func (m *mainLoop) Panorama() <-chan core.Panorama {
 	return m.panoramaData
@@ -137,6 +288,20 @@ func (m *mainLoop) TuneTo(f core.Frequency) {
 	})
 }
 
+// SetTXFrequency sets the frequency of the TX VFO for split operation.
+func (m *mainLoop) SetTXFrequency(f core.Frequency) {
+	m.q(func() {
+		m.vfo.SetTXFrequency(f)
+	})
+}
+
+// SetMode of the VFO.
+func (m *mainLoop) SetMode(mode string) {
+	m.q(func() {
+		m.vfo.SetMode(mode)
+	})
+}
+
 // TuneBy the given frequency.
 func (m *mainLoop) TuneBy(Δf core.Frequency) {
 	m.q(func() {
@@ -230,6 +395,130 @@ func (m *mainLoop) ShiftFrequencyRange(ratio core.Frct) {
 	})
 }
 
+// SelectFFTWindow selects the window function that is applied before the FFT.
+func (m *mainLoop) SelectFFTWindow(function core.WindowFunction) {
+	m.q(func() {
+		m.setFFTWindow(function)
+	})
+}
+
+// NextFFTWindow selects the next available window function.
+func (m *mainLoop) NextFFTWindow() {
+	m.q(func() {
+		m.setFFTWindow(m.fftWindow.Function.Next())
+	})
+}
+
+func (m *mainLoop) setFFTWindow(function core.WindowFunction) {
+	m.fftWindow.Function = function
+	m.dsp.SetWindow(m.fftWindow)
+}
+
+// SelectAveragingMode selects how the spectrum is averaged over time.
+func (m *mainLoop) SelectAveragingMode(mode core.AveragingMode) {
+	m.q(func() {
+		m.setAveragingMode(mode)
+	})
+}
+
+// NextAveragingMode selects the next available averaging mode.
+func (m *mainLoop) NextAveragingMode() {
+	m.q(func() {
+		m.setAveragingMode(m.averaging.Mode.Next())
+	})
+}
+
+func (m *mainLoop) setAveragingMode(mode core.AveragingMode) {
+	m.averaging.Mode = mode
+	m.dsp.SetAveraging(m.averaging)
+}
+
+// ResetHold resets the max-hold or min-hold trace.
+func (m *mainLoop) ResetHold() {
+	m.q(func() {
+		m.dsp.ResetHold()
+	})
+}
+
+// updateBand selects the peak detection preset of the current band. Without a preset for the band, the default
+// parameters are used. Live changes of the peak detection last until the band changes.
+func (m *mainLoop) updateBand() {
+	_, band := m.panorama.VFO()
+	if band.Name == m.band {
+		return
+	}
+	m.band = band.Name
+
+	preset, ok := m.peakPresets[band.Name]
+	if !ok {
+		preset = m.defaultPeakDetection
+	}
+	m.setPeakDetection(preset)
+}
+
+// SetPeakDetectionWindowSize sets the number of bins of the sliding window that is used to detect peaks.
+func (m *mainLoop) SetPeakDetectionWindowSize(size int) {
+	m.q(func() {
+		peakDetection := m.peakDetection
+		peakDetection.WindowSize = size
+		m.setPeakDetection(peakDetection)
+	})
+}
+
+// SetPeakThresholdOffset sets the rise in dB that starts a peak.
+func (m *mainLoop) SetPeakThresholdOffset(offset float64) {
+	m.q(func() {
+		peakDetection := m.peakDetection
+		peakDetection.ThresholdOffset = offset
+		m.setPeakDetection(peakDetection)
+	})
+}
+
+// SetMinPeakWidth sets the width of the narrowest peak that is detected.
+func (m *mainLoop) SetMinPeakWidth(width core.Frequency) {
+	m.q(func() {
+		peakDetection := m.peakDetection
+		peakDetection.MinWidth = width
+		m.setPeakDetection(peakDetection)
+	})
+}
+
+// SetPeakPersistence sets how long a peak is shown after it was detected for the last time.
+func (m *mainLoop) SetPeakPersistence(persistence time.Duration) {
+	m.q(func() {
+		peakDetection := m.peakDetection
+		peakDetection.Persistence = persistence
+		m.setPeakDetection(peakDetection)
+	})
+}
+
+func (m *mainLoop) setPeakDetection(peakDetection core.PeakDetection) {
+	m.peakDetection = peakDetection.Normalized()
+	m.dsp.SetPeakDetection(m.peakDetection)
+	m.panorama.SetPeakPersistence(m.peakDetection.Persistence)
+}
+
+// stopActivityLog writes the signals that are still active into the activity log before it is closed.
+func (m *mainLoop) stopActivityLog() {
+	m.panorama.FlushActivityLog()
+	if m.activityLog == nil {
+		return
+	}
+	m.activityLog.Close()
+	m.activityLog = nil
+}
+
+func (m *mainLoop) stopRecording() {
+	if m.recorder == nil {
+		return
+	}
+	err := m.recorder.Close()
+	if err != nil {
+		log.Printf("IQ recording failed: %v", err)
+	}
+	m.recorder = nil
+}
+
 type tuner struct {
 	lastDial time.Time
 }
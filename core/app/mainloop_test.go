@@ -4,9 +4,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ftl/hamradio/bandplan"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/ftl/panacotta/core"
+	"github.com/ftl/panacotta/core/bookmark"
 )
 
 func TestStopAndDone(t *testing.T) {
@@ -24,6 +26,239 @@ func TestStopAndDone(t *testing.T) {
 	assert.True(t, duration > 100*time.Millisecond)
 }
 
+func TestStopFlushesActivityLog(t *testing.T) {
+	panorama := &flushingPanorama{}
+	logger := &mockActivityLog{}
+	m := newMainLoop(&mockInput{}, &mockDSP{}, &mockVFO{}, panorama, 25)
+	m.activityLog = logger
+
+	stop := make(chan struct{})
+	close(stop)
+	m.Run(stop)
+
+	assert.True(t, panorama.flushed)
+	assert.True(t, logger.closed)
+	select {
+	case <-m.stopped:
+	default:
+		assert.Fail(t, "the main loop should be stopped")
+	}
+}
+
+type flushingPanorama struct {
+	mockPanorama
+	flushed bool
+}
+
+func (m *flushingPanorama) FlushActivityLog() {
+	m.flushed = true
+}
+
+type mockActivityLog struct {
+	closed bool
+}
+
+func (m *mockActivityLog) Close() {
+	m.closed = true
+}
+
+func TestPeakPresets(t *testing.T) {
+	dsp := &peakDSP{}
+	panorama := &bandPanorama{band: bandplan.Band{Name: bandplan.Band40m}}
+	m := newMainLoop(&mockInput{}, dsp, &mockVFO{}, panorama, 25)
+	m.defaultPeakDetection = core.PeakDetection{WindowSize: 9, ThresholdOffset: 3, Persistence: 10 * time.Second}
+	m.peakPresets = map[bandplan.BandName]core.PeakDetection{
+		bandplan.Band40m: {WindowSize: 15, ThresholdOffset: 6, Persistence: 5 * time.Second},
+	}
+
+	m.updateBand()
+	assert.Equal(t, 15, dsp.peakDetection.WindowSize)
+	assert.Equal(t, 5*time.Second, panorama.persistence)
+
+	m.setPeakDetection(core.PeakDetection{WindowSize: 4})
+	assert.Equal(t, 5, dsp.peakDetection.WindowSize, "window size must be odd")
+
+	m.updateBand()
+	assert.Equal(t, 5, dsp.peakDetection.WindowSize, "same band keeps live changes")
+
+	panorama.band = bandplan.Band{Name: bandplan.Band20m}
+	m.updateBand()
+	assert.Equal(t, m.defaultPeakDetection, dsp.peakDetection)
+	assert.Equal(t, 10*time.Second, panorama.persistence)
+}
+
+func TestSkimmerGetsAllSamples(t *testing.T) {
+	dsp := &countingDSP{}
+	skimmer := &mockSkimmer{}
+	m := newMainLoop(&mockInput{}, dsp, &mockVFO{}, &mockPanorama{}, 25)
+	m.skimmer = skimmer
+
+	m.processSamples(make([]complex128, 10))
+	m.processSamples(make([]complex128, 10))
+
+	assert.Equal(t, 1, dsp.blocks, "the DSP only needs the samples for the next redraw")
+	assert.Equal(t, 2, skimmer.blocks)
+}
+
+func TestFreezeOnTX(t *testing.T) {
+	dsp := &countingDSP{}
+	skimmer := &mockSkimmer{}
+	m := newMainLoop(&mockInput{}, dsp, &mockVFO{}, &mockPanorama{}, 25)
+	m.skimmer = skimmer
+	m.streaming = true
+	m.freezeOnTX = true
+
+	m.updatePTT(true)
+	m.processSamples(make([]complex128, 10))
+
+	assert.Equal(t, 0, dsp.blocks)
+	assert.Equal(t, 0, skimmer.blocks)
+	assert.Equal(t, 0, dsp.resets)
+
+	m.updatePTT(false)
+	m.processSamples(make([]complex128, 10))
+
+	assert.Equal(t, 1, dsp.blocks)
+	assert.Equal(t, 1, skimmer.blocks)
+	assert.Equal(t, 1, dsp.resets, "the averaging starts over after the transmission")
+}
+
+func TestAddSpot(t *testing.T) {
+	panorama := &spotPanorama{}
+	m := newMainLoop(&mockInput{}, &mockDSP{}, &mockVFO{}, panorama, 25)
+
+	m.addSpot(core.Spot{Call: "K1A"})
+	m.addSpot(core.Spot{Call: "DL1ABC", Skimmer: true})
+
+	assert.Equal(t, []string{"K1A"}, panorama.spots)
+	assert.Equal(t, []string{"DL1ABC"}, panorama.skimmerSpots)
+}
+
+type spotPanorama struct {
+	mockPanorama
+	spots        []string
+	skimmerSpots []string
+}
+
+func (m *spotPanorama) SetSpot(spot core.Spot) {
+	m.spots = append(m.spots, spot.Call)
+}
+
+func (m *spotPanorama) SetSkimmerSpot(spot core.Spot) {
+	m.skimmerSpots = append(m.skimmerSpots, spot.Call)
+}
+
+func TestBookmarks(t *testing.T) {
+	store := bookmark.NewStore("")
+	store.Add(core.Bookmark{Frequency: 7030000, Mode: "CW"})
+	vfo := &tuningVFO{}
+	panorama := &bookmarkPanorama{}
+	panorama.vfo.Frequency = 7010000
+	m := newMainLoop(&mockInput{}, &mockDSP{}, vfo, panorama, 25)
+	m.bookmarks = &unsavedBookmarks{store}
+
+	m.AddBookmark()
+	(<-m.command)()
+	assert.Equal(t, []core.Bookmark{{Frequency: 7010000, Label: "M2"}, {Frequency: 7030000, Mode: "CW", Label: "M1"}}, panorama.bookmarks)
+
+	m.NextBookmark()
+	(<-m.command)()
+	assert.Equal(t, core.Frequency(7030000), vfo.frequency)
+	assert.Equal(t, "CW", vfo.mode)
+
+	panorama.vfo.Frequency = 7010200
+	m.DeleteBookmark()
+	(<-m.command)()
+	assert.Equal(t, []core.Bookmark{{Frequency: 7030000, Mode: "CW", Label: "M1"}}, panorama.bookmarks)
+}
+
+type unsavedBookmarks struct {
+	*bookmark.Store
+}
+
+func (b *unsavedBookmarks) Save() error {
+	return nil
+}
+
+type tuningVFO struct {
+	mockVFO
+	frequency core.Frequency
+	mode      string
+}
+
+func (m *tuningVFO) TuneTo(f core.Frequency) {
+	m.frequency = f
+}
+
+func (m *tuningVFO) SetMode(mode string) {
+	m.mode = mode
+}
+
+type bookmarkPanorama struct {
+	mockPanorama
+	vfo       core.VFO
+	bookmarks []core.Bookmark
+}
+
+func (m *bookmarkPanorama) VFO() (core.VFO, bandplan.Band) {
+	return m.vfo, bandplan.UnknownBand
+}
+
+func (m *bookmarkPanorama) SetBookmarks(bookmarks []core.Bookmark) {
+	m.bookmarks = bookmarks
+}
+
+type countingDSP struct {
+	mockDSP
+	blocks int
+	resets int
+}
+
+func (m *countingDSP) ProcessSamples([]complex128, float64, core.FrequencyRange, core.VFO) {
+	m.blocks++
+}
+
+func (m *countingDSP) ResetAveraging() {
+	m.resets++
+}
+
+type mockSkimmer struct {
+	blocks int
+}
+
+func (m *mockSkimmer) Write([]complex128) {
+	m.blocks++
+}
+
+func (m *mockSkimmer) SetCarriers(core.VFO, []core.Frequency) {}
+
+func (m *mockSkimmer) Texts() <-chan core.CWText {
+	return nil
+}
+
+type peakDSP struct {
+	mockDSP
+	peakDetection core.PeakDetection
+}
+
+func (m *peakDSP) SetPeakDetection(peakDetection core.PeakDetection) {
+	m.peakDetection = peakDetection
+}
+
+type bandPanorama struct {
+	mockPanorama
+	band        bandplan.Band
+	persistence time.Duration
+}
+
+func (m *bandPanorama) VFO() (core.VFO, bandplan.Band) {
+	return core.VFO{}, m.band
+}
+
+func (m *bandPanorama) SetPeakPersistence(persistence time.Duration) {
+	m.persistence = persistence
+}
+
 type mockInput struct{}
 
 func (m *mockInput) Samples() <-chan []complex128 {
@@ -44,18 +279,33 @@ func (m *mockVFO) TuneBy(Δf core.Frequency) {}
 
 func (m *mockVFO) TuneTo(f core.Frequency) {}
 
+func (m *mockVFO) SetMode(mode string) {}
+
+func (m *mockVFO) SetTXFrequency(f core.Frequency) {}
+
 type mockDSP struct{}
 
-func (m *mockDSP) ProcessSamples(samples []complex128, fftRange core.FrequencyRange, vfo core.VFO) {}
+func (m *mockDSP) ProcessSamples(samples []complex128, clipping float64, fftRange core.FrequencyRange, vfo core.VFO) {
+}
 
 func (m *mockDSP) FFT() chan core.FFT {
 	return make(chan core.FFT)
 }
 
+func (m *mockDSP) SetWindow(core.FFTWindow) {}
+
+func (m *mockDSP) SetAveraging(core.Averaging) {}
+
+func (m *mockDSP) SetPeakDetection(core.PeakDetection) {}
+
+func (m *mockDSP) ResetHold() {}
+
+func (m *mockDSP) ResetAveraging() {}
+
 type mockPanorama struct{}
 
-func (m *mockPanorama) VFO() (core.VFO, core.Band) {
-	return core.VFO{}, core.UnknownBand
+func (m *mockPanorama) VFO() (core.VFO, bandplan.Band) {
+	return core.VFO{}, bandplan.UnknownBand
 }
 
 func (m *mockPanorama) FrequencyRange() core.FrequencyRange {
@@ -68,16 +318,36 @@ func (m *mockPanorama) SetFFT(core.FFT) {}
 
 func (m *mockPanorama) SetVFO(core.VFO) {}
 
+func (m *mockPanorama) SetInputState(core.InputState) {}
+
+func (m *mockPanorama) SetPeakPersistence(time.Duration) {}
+
+func (m *mockPanorama) SetCWText(core.CWText) {}
+
+func (m *mockPanorama) SetSpot(core.Spot) {}
+
+func (m *mockPanorama) SetSkimmerSpot(core.Spot) {}
+
+func (m *mockPanorama) SetBookmarks([]core.Bookmark) {}
+
+func (m *mockPanorama) FlushActivityLog() {}
+
 func (m *mockPanorama) Data() core.Panorama {
 	return core.Panorama{}
 }
 
-func (m *mockPanorama) TuneTo(core.Frequency) {}
+func (m *mockPanorama) ToggleSignalDetection() {}
 
-func (m *mockPanorama) TuneBy(core.Frequency) {}
+func (m *mockPanorama) SignalDetectionActive() bool {
+	return false
+}
 
 func (m *mockPanorama) ToggleViewMode() {}
 
+func (m *mockPanorama) ViewMode() core.ViewMode {
+	return core.ViewFixed
+}
+
 func (m *mockPanorama) ZoomIn() {}
 
 func (m *mockPanorama) ZoomOut() {}
@@ -85,3 +355,11 @@ func (m *mockPanorama) ZoomOut() {}
 func (m *mockPanorama) ZoomToBand() {}
 
 func (m *mockPanorama) ResetZoom() {}
+
+func (m *mockPanorama) FinerDynamicRange() {}
+
+func (m *mockPanorama) CoarserDynamicRange() {}
+
+func (m *mockPanorama) ShiftDynamicRange(core.Frct) {}
+
+func (m *mockPanorama) ShiftFrequencyRange(core.Frct) {}
@@ -0,0 +1,63 @@
+package app
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/ftl/panacotta/core/iq"
+)
+
+// StartRecording of the raw IQ samples into a new file in the configured recording directory.
+func (c *Controller) StartRecording() {
+	c.q(c.startRecording)
+}
+
+// StopRecording of the raw IQ samples.
+func (c *Controller) StopRecording() {
+	c.q(c.stopRecording)
+}
+
+// ToggleRecording of the raw IQ samples.
+func (c *Controller) ToggleRecording() {
+	c.q(func() {
+		if c.recorder == nil {
+			c.startRecording()
+		} else {
+			c.stopRecording()
+		}
+	})
+}
+
+func (c *Controller) startRecording() {
+	if c.recorder != nil {
+		return
+	}
+
+	format, err := iq.ParseFormat(c.config.RecordingFormat)
+	if err != nil {
+		log.Printf("Cannot start IQ recording: %v", err)
+		return
+	}
+
+	vfo, _ := c.panorama.VFO()
+	start := time.Now()
+	metadata := iq.Metadata{
+		Format:     format,
+		SampleRate: c.config.SampleRate,
+		IFCenter:   c.config.IFCenter,
+		RXCenter:   c.config.RXCenter(),
+		VFO:        vfo.Frequency,
+		Start:      start,
+	}
+	filename := filepath.Join(c.config.RecordingDirectory, fmt.Sprintf("panacotta_%s_%.0fHz.%s", start.UTC().Format("20060102_150405Z"), vfo.Frequency, format))
+
+	recorder, err := iq.NewRecorder(filename, metadata)
+	if err != nil {
+		log.Printf("Cannot start IQ recording: %v", err)
+		return
+	}
+	log.Printf("IQ recording to %s", filename)
+	c.recorder = recorder
+}
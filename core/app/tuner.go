@@ -0,0 +1,55 @@
+package app
+
+import (
+	"log"
+
+	"github.com/ftl/panacotta/core"
+)
+
+// SetTunerGain switches the tuner to manual gain mode with the given gain in dB.
+func (c *Controller) SetTunerGain(gain core.DB) {
+	c.updateTunerSettings(func(settings *core.TunerSettings) {
+		settings.ManualGain = true
+		settings.Gain = gain
+	})
+}
+
+// SetAutomaticTunerGain switches the tuner to automatic gain mode.
+func (c *Controller) SetAutomaticTunerGain() {
+	c.updateTunerSettings(func(settings *core.TunerSettings) {
+		settings.ManualGain = false
+	})
+}
+
+// SetRTLAGC switches the digital AGC of the RTL2832 on or off.
+func (c *Controller) SetRTLAGC(on bool) {
+	c.updateTunerSettings(func(settings *core.TunerSettings) {
+		settings.AGC = on
+	})
+}
+
+// SetTunerBandwidth in Hz, 0 selects the bandwidth automatically.
+func (c *Controller) SetTunerBandwidth(bandwidth int) {
+	c.updateTunerSettings(func(settings *core.TunerSettings) {
+		settings.Bandwidth = bandwidth
+	})
+}
+
+func (c *Controller) updateTunerSettings(update func(*core.TunerSettings)) {
+	c.q(func() {
+		tuner, ok := c.samplesInput.(core.TunerControl)
+		if !ok {
+			log.Print("The samples input does not support tuner settings")
+			return
+		}
+
+		settings := c.config.Tuner
+		update(&settings)
+		err := tuner.SetTunerSettings(settings)
+		if err != nil {
+			log.Printf("Cannot change the tuner settings: %v", err)
+			return
+		}
+		c.config.Tuner = settings
+	})
+}
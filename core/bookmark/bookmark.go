@@ -0,0 +1,141 @@
+// Package bookmark provides a persistent store of frequency bookmarks.
+package bookmark
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/ftl/panacotta/core"
+)
+
+// Filename of the bookmark store within the configuration directory.
+const Filename = "panacotta.bookmarks"
+
+// NewStore returns a new empty store that is persisted in the given directory.
+func NewStore(directory string) *Store {
+	return &Store{filename: filepath.Join(directory, Filename)}
+}
+
+// Store of bookmarks, sorted by frequency.
+type Store struct {
+	filename  string
+	bookmarks []core.Bookmark
+}
+
+// Load the bookmarks from the file. A missing file is not an error, the store is empty then.
+func (s *Store) Load() error {
+	buffer, err := ioutil.ReadFile(s.filename)
+	if os.IsNotExist(err) {
+		s.bookmarks = nil
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "cannot read the bookmarks")
+	}
+
+	var bookmarks []core.Bookmark
+	err = json.Unmarshal(buffer, &bookmarks)
+	if err != nil {
+		return errors.Wrapf(err, "cannot parse the bookmarks in %s", s.filename)
+	}
+	s.bookmarks = bookmarks
+	s.sort()
+	return nil
+}
+
+// Save the bookmarks to the file.
+func (s *Store) Save() error {
+	buffer, err := json.MarshalIndent(s.bookmarks, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "cannot encode the bookmarks")
+	}
+	err = ioutil.WriteFile(s.filename, buffer, 0644)
+	if err != nil {
+		return errors.Wrap(err, "cannot write the bookmarks")
+	}
+	return nil
+}
+
+// All bookmarks, sorted by frequency.
+func (s *Store) All() []core.Bookmark {
+	result := make([]core.Bookmark, len(s.bookmarks))
+	copy(result, s.bookmarks)
+	return result
+}
+
+// Add the given bookmark. If the bookmark has no label, it is labelled as the next free memory channel.
+func (s *Store) Add(bookmark core.Bookmark) core.Bookmark {
+	if bookmark.Label == "" {
+		bookmark.Label = s.nextChannel()
+	}
+	s.bookmarks = append(s.bookmarks, bookmark)
+	s.sort()
+	return bookmark
+}
+
+func (s *Store) nextChannel() string {
+	labels := make(map[string]bool, len(s.bookmarks))
+	for _, bookmark := range s.bookmarks {
+		labels[bookmark.Label] = true
+	}
+	for i := 1; ; i++ {
+		label := fmt.Sprintf("M%d", i)
+		if !labels[label] {
+			return label
+		}
+	}
+}
+
+// Delete the bookmark nearest to the given frequency within the given tolerance. The result is false if there is no
+// such bookmark.
+func (s *Store) Delete(f core.Frequency, tolerance core.Frequency) (core.Bookmark, bool) {
+	index := -1
+	distance := tolerance
+	for i, bookmark := range s.bookmarks {
+		d := core.Frequency(math.Abs(float64(bookmark.Frequency - f)))
+		if d <= distance {
+			index = i
+			distance = d
+		}
+	}
+	if index == -1 {
+		return core.Bookmark{}, false
+	}
+
+	result := s.bookmarks[index]
+	s.bookmarks = append(s.bookmarks[:index], s.bookmarks[index+1:]...)
+	return result, true
+}
+
+// Next returns the first bookmark above the given frequency. The result is false if there is no such bookmark.
+func (s *Store) Next(f core.Frequency) (core.Bookmark, bool) {
+	for _, bookmark := range s.bookmarks {
+		if bookmark.Frequency > f {
+			return bookmark, true
+		}
+	}
+	return core.Bookmark{}, false
+}
+
+// Previous returns the first bookmark below the given frequency. The result is false if there is no such bookmark.
+func (s *Store) Previous(f core.Frequency) (core.Bookmark, bool) {
+	for i := len(s.bookmarks) - 1; i >= 0; i-- {
+		if s.bookmarks[i].Frequency < f {
+			return s.bookmarks[i], true
+		}
+	}
+	return core.Bookmark{}, false
+}
+
+func (s *Store) sort() {
+	sort.SliceStable(s.bookmarks, func(i, j int) bool {
+		return s.bookmarks[i].Frequency < s.bookmarks[j].Frequency
+	})
+}
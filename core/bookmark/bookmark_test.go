@@ -0,0 +1,75 @@
+package bookmark
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ftl/panacotta/core"
+)
+
+func TestRoundtrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "panacotta-bookmarks")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	store := NewStore(dir)
+	require.NoError(t, store.Load(), "a missing file is not an error")
+	assert.Empty(t, store.All())
+
+	store.Add(core.Bookmark{Frequency: 7074000, Mode: "PKTUSB", Label: "FT8", Color: "#ff8000"})
+	store.Add(core.Bookmark{Frequency: 7030000, Mode: "CW"})
+	require.NoError(t, store.Save())
+
+	loaded := NewStore(dir)
+	require.NoError(t, loaded.Load())
+	assert.Equal(t, []core.Bookmark{
+		{Frequency: 7030000, Mode: "CW", Label: "M1"},
+		{Frequency: 7074000, Mode: "PKTUSB", Label: "FT8", Color: "#ff8000"},
+	}, loaded.All())
+}
+
+func TestMemoryChannelLabels(t *testing.T) {
+	store := NewStore("")
+	assert.Equal(t, "M1", store.Add(core.Bookmark{Frequency: 7010000}).Label)
+	assert.Equal(t, "M2", store.Add(core.Bookmark{Frequency: 7020000}).Label)
+	store.Delete(7010000, 0)
+	assert.Equal(t, "M1", store.Add(core.Bookmark{Frequency: 7030000}).Label)
+}
+
+func TestDelete(t *testing.T) {
+	store := NewStore("")
+	store.Add(core.Bookmark{Frequency: 7010000})
+	store.Add(core.Bookmark{Frequency: 7010400})
+	store.Add(core.Bookmark{Frequency: 7020000})
+
+	_, ok := store.Delete(7015000, 500)
+	assert.False(t, ok)
+
+	deleted, ok := store.Delete(7010300, 500)
+	assert.True(t, ok)
+	assert.Equal(t, core.Frequency(7010400), deleted.Frequency)
+	assert.Equal(t, 2, len(store.All()))
+}
+
+func TestNextAndPrevious(t *testing.T) {
+	store := NewStore("")
+	store.Add(core.Bookmark{Frequency: 7020000})
+	store.Add(core.Bookmark{Frequency: 7010000})
+	store.Add(core.Bookmark{Frequency: 7030000})
+
+	next, ok := store.Next(7010000)
+	assert.True(t, ok)
+	assert.Equal(t, core.Frequency(7020000), next.Frequency)
+	_, ok = store.Next(7030000)
+	assert.False(t, ok)
+
+	previous, ok := store.Previous(7025000)
+	assert.True(t, ok)
+	assert.Equal(t, core.Frequency(7020000), previous.Frequency)
+	_, ok = store.Previous(7010000)
+	assert.False(t, ok)
+}
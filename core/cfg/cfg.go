@@ -1,40 +1,270 @@
 package cfg
 
 import (
+	"log"
+	"time"
+
+	"github.com/ftl/hamradio/bandplan"
 	"github.com/ftl/hamradio/cfg"
 
 	"github.com/ftl/panacotta/core"
 )
 
 const (
+	deviceIndex         cfg.Key = "panacotta.device.index"
+	deviceSerial        cfg.Key = "panacotta.device.serial"
 	testmode            cfg.Key = "panacotta.testmode"
+	testfile            cfg.Key = "panacotta.testfile"
+	testfileLoop        cfg.Key = "panacotta.testfileLoop"
 	frequencyCorrection cfg.Key = "panacotta.frequencyCorrection"
 	vfoHost             cfg.Key = "panacotta.vfoHost"
+	rtltcpHost          cfg.Key = "panacotta.rtltcpHost"
+	rtltcpServer        cfg.Key = "panacotta.rtltcpServer"
 	fftPerSecond        cfg.Key = "panacotta.fftPerSecond"
+	fftWindow           cfg.Key = "panacotta.fft.window"
+	fftKaiserBeta       cfg.Key = "panacotta.fft.kaiserBeta"
+	fftOverlap          cfg.Key = "panacotta.fft.overlap"
+	averagingMode       cfg.Key = "panacotta.averaging.mode"
+	averagingDepth      cfg.Key = "panacotta.averaging.depth"
+	averagingAlpha      cfg.Key = "panacotta.averaging.alpha"
+	dspWorkers          cfg.Key = "panacotta.dsp.workers"
+	peakDetection       cfg.Key = "panacotta.peaks"
+	peakPresets         cfg.Key = "panacotta.peaks.presets"
 	dynamicRangeFrom    cfg.Key = "panacotta.dynamicRange.from"
 	dynamicRangeTo      cfg.Key = "panacotta.dynamicRange.to"
+	rig                 cfg.Key = "panacotta.rig"
+	ifCenter            cfg.Key = "panacotta.ifCenter"
+	sampleRate          cfg.Key = "panacotta.sampleRate"
+	blockSize           cfg.Key = "panacotta.blockSize"
+	rxOffset            cfg.Key = "panacotta.rxOffset"
+	recordingDirectory  cfg.Key = "panacotta.recording.directory"
+	recordingFormat     cfg.Key = "panacotta.recording.format"
+	activityLog         cfg.Key = "panacotta.activity.enabled"
+	activityLogFormat   cfg.Key = "panacotta.activity.format"
+	cwSkimmer           cfg.Key = "panacotta.cw.skimmer"
+	iaruRegion          cfg.Key = "panacotta.bandplan.region"
+	segmentWarning      cfg.Key = "panacotta.bandplan.warning"
+	freezeOnTX          cfg.Key = "panacotta.tx.freeze"
+	clusterAddress      cfg.Key = "panacotta.cluster.address"
+	clusterCallsign     cfg.Key = "panacotta.cluster.callsign"
+	rbnAddress          cfg.Key = "panacotta.rbn.address"
+	spotMaxAge          cfg.Key = "panacotta.cluster.maxAge"
+	tunerManualGain     cfg.Key = "panacotta.tuner.manualGain"
+	tunerGain           cfg.Key = "panacotta.tuner.gain"
+	tunerAGC            cfg.Key = "panacotta.tuner.agc"
+	tunerBandwidth      cfg.Key = "panacotta.tuner.bandwidth"
+	iqSwap              cfg.Key = "panacotta.iq.swap"
+	iqInvert            cfg.Key = "panacotta.iq.invert"
+	iqRemoveDC          cfg.Key = "panacotta.iq.removeDC"
+	iqCorrectImbalance  cfg.Key = "panacotta.iq.correctImbalance"
+)
+
+const (
+	defaultKaiserBeta = 8.6
+	defaultFFTOverlap = 0.0
+)
+
+var (
+	defaultAveraging     = core.Averaging{Mode: core.AveragingMoving, Depth: 5, Alpha: 0.3}
+	defaultPeakDetection = core.PeakDetection{WindowSize: 9, ThresholdOffset: 3, Persistence: 10 * time.Second}
+	defaultSpotMaxAge    = 10 * time.Minute
 )
 
+// RigProfile contains the parameters of the IF tap of a specific transceiver model.
+type RigProfile struct {
+	IFCenter   core.Frequency
+	SampleRate int
+	BlockSize  int // this is the number of *complex* samples in one block
+}
+
+// DefaultRig is the name of the rig profile that is used if nothing else is configured.
+const DefaultRig = "ft450d"
+
+// RigProfiles contains all known rig profiles by name.
+var RigProfiles = map[string]RigProfile{
+	"ft450d": {
+		IFCenter:   67899000,
+		SampleRate: 1800000,
+		BlockSize:  65536,
+	},
+	"ft991": {
+		IFCenter:   69450000,
+		SampleRate: 1800000,
+		BlockSize:  65536,
+	},
+	// The IC-7300 is a direct sampling rig, the IF depends on the IF output kit in use;
+	// override panacotta.ifCenter if your kit uses a different frequency.
+	"ic7300-ifout": {
+		IFCenter:   36000000,
+		SampleRate: 1800000,
+		BlockSize:  65536,
+	},
+	"ts590": {
+		IFCenter:   73095000,
+		SampleRate: 1800000,
+		BlockSize:  65536,
+	},
+}
+
 func Load() (core.Configuration, error) {
 	configuration, err := cfg.LoadDefault()
 	if err != nil {
 		return core.Configuration{}, err
 	}
 
+	windowName := configuration.Get(fftWindow, core.WindowBlackmanHarris.String()).(string)
+	window, ok := core.ParseWindowFunction(windowName)
+	if !ok {
+		log.Printf("unknown FFT window %q, using %q instead", windowName, core.WindowBlackmanHarris)
+		window = core.WindowBlackmanHarris
+	}
+
+	averagingName := configuration.Get(averagingMode, defaultAveraging.Mode.String()).(string)
+	averaging, ok := core.ParseAveragingMode(averagingName)
+	if !ok {
+		log.Printf("unknown averaging mode %q, using %q instead", averagingName, defaultAveraging.Mode)
+		averaging = defaultAveraging.Mode
+	}
+
+	detection := readPeakDetection(configuration.Get(peakDetection, map[string]interface{}{}).(map[string]interface{}), defaultPeakDetection)
+	presets := make(map[bandplan.BandName]core.PeakDetection)
+	configuration.GetSlice(peakPresets, func(i int, preset map[string]interface{}) {
+		band, ok := preset["band"].(string)
+		if !ok {
+			log.Printf("peak detection preset %d has no band, ignoring it", i)
+			return
+		}
+		presets[bandplan.BandName(band)] = readPeakDetection(preset, detection)
+	})
+
+	rigName := configuration.Get(rig, DefaultRig).(string)
+	profile, ok := RigProfiles[rigName]
+	if !ok {
+		log.Printf("unknown rig profile %q, using %q instead", rigName, DefaultRig)
+		rigName = DefaultRig
+		profile = RigProfiles[DefaultRig]
+	}
+
 	result := core.Configuration{
+		DeviceIndex:         int(configuration.Get(deviceIndex, 0.0).(float64)),
+		DeviceSerial:        configuration.Get(deviceSerial, "").(string),
 		Testmode:            configuration.Get(testmode, false).(bool),
+		Testfile:            configuration.Get(testfile, "").(string),
+		TestfileLoop:        configuration.Get(testfileLoop, true).(bool),
 		FrequencyCorrection: int(configuration.Get(frequencyCorrection, 0.0).(float64)),
 		VFOHost:             configuration.Get(vfoHost, "").(string),
+		RTLTCPHost:          configuration.Get(rtltcpHost, "").(string),
+		RTLTCPServer:        configuration.Get(rtltcpServer, "").(string),
 		FFTPerSecond:        int(configuration.Get(fftPerSecond, 25.0).(float64)),
+		FFTWindow: core.FFTWindow{
+			Function:   window,
+			KaiserBeta: configuration.Get(fftKaiserBeta, defaultKaiserBeta).(float64),
+		},
+		FFTOverlap: configuration.Get(fftOverlap, defaultFFTOverlap).(float64),
+		Averaging: core.Averaging{
+			Mode:  averaging,
+			Depth: int(configuration.Get(averagingDepth, float64(defaultAveraging.Depth)).(float64)),
+			Alpha: configuration.Get(averagingAlpha, defaultAveraging.Alpha).(float64),
+		},
+		PeakDetection: detection,
+		PeakPresets:   presets,
+		DSPWorkers:    int(configuration.Get(dspWorkers, 0.0).(float64)),
 		DynamicRange: core.DBRange{
 			From: core.DB(configuration.Get(dynamicRangeFrom, -105.0).(float64)),
 			To:   core.DB(configuration.Get(dynamicRangeTo, 15.0).(float64)),
 		}.Normalized(),
+
+		Rig:        rigName,
+		IFCenter:   core.Frequency(configuration.Get(ifCenter, float64(profile.IFCenter)).(float64)),
+		SampleRate: int(configuration.Get(sampleRate, float64(profile.SampleRate)).(float64)),
+		BlockSize:  int(configuration.Get(blockSize, float64(profile.BlockSize)).(float64)),
+
+		RecordingDirectory: configuration.Get(recordingDirectory, "").(string),
+		RecordingFormat:    configuration.Get(recordingFormat, "wav").(string),
+
+		ActivityLog:       configuration.Get(activityLog, false).(bool),
+		ActivityLogFormat: configuration.Get(activityLogFormat, "csv").(string),
+
+		CWSkimmer: configuration.Get(cwSkimmer, false).(bool),
+
+		IARURegion:     int(configuration.Get(iaruRegion, 1.0).(float64)),
+		SegmentWarning: configuration.Get(segmentWarning, false).(bool),
+
+		FreezeOnTX: configuration.Get(freezeOnTX, true).(bool),
+
+		ClusterAddress:  configuration.Get(clusterAddress, "").(string),
+		RBNAddress:      configuration.Get(rbnAddress, "").(string),
+		ClusterCallsign: configuration.Get(clusterCallsign, "").(string),
+		SpotMaxAge:      time.Duration(configuration.Get(spotMaxAge, defaultSpotMaxAge.Seconds()).(float64)) * time.Second,
+
+		Tuner: core.TunerSettings{
+			ManualGain: configuration.Get(tunerManualGain, false).(bool),
+			Gain:       core.DB(configuration.Get(tunerGain, 0.0).(float64)),
+			AGC:        configuration.Get(tunerAGC, false).(bool),
+			Bandwidth:  int(configuration.Get(tunerBandwidth, 0.0).(float64)),
+		},
+
+		IQCorrection: core.IQCorrection{
+			SwapIQ:           configuration.Get(iqSwap, false).(bool),
+			InvertSpectrum:   configuration.Get(iqInvert, false).(bool),
+			RemoveDC:         configuration.Get(iqRemoveDC, true).(bool),
+			CorrectImbalance: configuration.Get(iqCorrectImbalance, false).(bool),
+		},
+	}
+
+	if offset, ok := configuration.Get(rxOffset, nil).(float64); ok {
+		result.RXOffset = core.Frequency(offset)
+		result.FixedRXOffset = true
 	}
 
 	return result, nil
 }
 
+// readPeakDetection reads the peak detection parameters from the given values. Missing parameters are taken from the
+// given defaults.
+func readPeakDetection(values map[string]interface{}, defaults core.PeakDetection) core.PeakDetection {
+	result := defaults
+	if windowSize, ok := values["windowSize"].(float64); ok {
+		result.WindowSize = int(windowSize)
+	}
+	if thresholdOffset, ok := values["thresholdOffset"].(float64); ok {
+		result.ThresholdOffset = thresholdOffset
+	}
+	if minWidth, ok := values["minWidth"].(float64); ok {
+		result.MinWidth = core.Frequency(minWidth)
+	}
+	if persistence, ok := values["persistence"].(float64); ok {
+		result.Persistence = time.Duration(persistence * float64(time.Second))
+	}
+	return result.Normalized()
+}
+
 func Static() core.Configuration {
-	return core.Configuration{}
+	profile := RigProfiles[DefaultRig]
+	return core.Configuration{
+		TestfileLoop:  true,
+		FFTPerSecond:  25,
+		FFTWindow:     core.FFTWindow{Function: core.WindowBlackmanHarris, KaiserBeta: defaultKaiserBeta},
+		FFTOverlap:    defaultFFTOverlap,
+		Averaging:     defaultAveraging,
+		PeakDetection: defaultPeakDetection,
+		DynamicRange:  core.DBRange{From: -105, To: 15},
+
+		Rig:        DefaultRig,
+		IFCenter:   profile.IFCenter,
+		SampleRate: profile.SampleRate,
+		BlockSize:  profile.BlockSize,
+
+		RecordingFormat:   "wav",
+		ActivityLogFormat: "csv",
+
+		IARURegion: 1,
+		FreezeOnTX: true,
+
+		SpotMaxAge: defaultSpotMaxAge,
+
+		IQCorrection: core.IQCorrection{
+			RemoveDC: true,
+		},
+	}
 }
@@ -0,0 +1,99 @@
+package cluster
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ftl/panacotta/core"
+)
+
+const spotBufferSize = 64
+
+// Dial connects to the DX cluster at the given address, logs in with the given callsign and starts reading spots.
+func Dial(address string, callsign string) (*Client, error) {
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot open DX cluster connection")
+	}
+
+	_, err = fmt.Fprintf(conn, "%s\r\n", callsign)
+	if err != nil {
+		conn.Close()
+		return nil, errors.Wrap(err, "cannot login to the DX cluster")
+	}
+	log.Printf("DX cluster %s: logged in as %s", address, callsign)
+
+	result := Client{
+		conn:      conn,
+		spots:     make(chan core.Spot, spotBufferSize),
+		asyncRead: new(sync.WaitGroup),
+	}
+
+	result.asyncRead.Add(1)
+	go result.readSpots()
+
+	return &result, nil
+}
+
+// Client of a DX cluster.
+type Client struct {
+	conn      net.Conn
+	spots     chan core.Spot
+	asyncRead *sync.WaitGroup
+	closeLock sync.Mutex
+	closed    bool
+}
+
+func (c *Client) readSpots() {
+	defer c.asyncRead.Done()
+	scanner := bufio.NewScanner(c.conn)
+	for scanner.Scan() {
+		spot, ok := ParseSpot(scanner.Text(), time.Now())
+		if !ok {
+			continue
+		}
+		select {
+		case c.spots <- spot:
+		default:
+			log.Print("DX cluster buffer overflow, dropping spot")
+		}
+	}
+	if c.isClosed() {
+		return
+	}
+	if err := scanner.Err(); err != nil {
+		log.Printf("DX cluster connection lost: %v", err)
+	} else {
+		log.Print("DX cluster connection closed by the server")
+	}
+}
+
+func (c *Client) isClosed() bool {
+	c.closeLock.Lock()
+	defer c.closeLock.Unlock()
+	return c.closed
+}
+
+// Spots received from the DX cluster.
+func (c *Client) Spots() <-chan core.Spot {
+	return c.spots
+}
+
+// Close the connection.
+func (c *Client) Close() error {
+	defer log.Print("DX cluster shutdown")
+	c.closeLock.Lock()
+	c.closed = true
+	err := c.conn.Close()
+	c.closeLock.Unlock()
+
+	c.asyncRead.Wait()
+	close(c.spots)
+	return err
+}
@@ -0,0 +1,90 @@
+package cluster
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ftl/panacotta/core"
+)
+
+// standIn is a minimal DX cluster that records the login and sends the given lines.
+func standIn(t *testing.T, lines ...string) (string, <-chan string) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	logins := make(chan string, 1)
+	go func() {
+		defer listener.Close()
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		conn.Write([]byte("login: "))
+		login, err := bufio.NewReader(conn).ReadString('\n')
+		if err != nil {
+			return
+		}
+		logins <- login
+		for _, line := range lines {
+			conn.Write([]byte(line + "\r\n"))
+		}
+		time.Sleep(100 * time.Millisecond)
+	}()
+
+	return listener.Addr().String(), logins
+}
+
+func TestClientReceivesSpots(t *testing.T) {
+	address, logins := standIn(t,
+		"Hello DL1ABC, this is GB7DXC",
+		"DX de DL2XYZ:     14025.0  K1A          CW 599                         1234Z",
+		"DL1ABC de GB7DXC  6-Jun-2020 1240Z dxspider >",
+		"DX de W3LPL:      7185.5  EA8XYZ       SSB                            1235Z FN20",
+	)
+
+	client, err := Dial(address, "DL1ABC")
+	require.NoError(t, err)
+	defer client.Close()
+
+	assert.Equal(t, "DL1ABC\r\n", <-logins)
+	assert.Equal(t, core.Spot{Call: "K1A", Frequency: 14025000}, trim(<-client.Spots()))
+	assert.Equal(t, core.Spot{Call: "EA8XYZ", Frequency: 7185500}, trim(<-client.Spots()))
+}
+
+func TestClientReceivesSkimmerSpots(t *testing.T) {
+	address, _ := standIn(t,
+		"Please enter your call:",
+		"DX de EA5WU-#:    7023.5  DL1ABC       CW    22 dB  28 WPM  CQ      1234Z",
+	)
+
+	client, err := Dial(address, "DL1ABC")
+	require.NoError(t, err)
+	defer client.Close()
+
+	spot := <-client.Spots()
+	assert.True(t, spot.Skimmer)
+	assert.Equal(t, core.DB(22), spot.SNR)
+	assert.Equal(t, 28, spot.WPM)
+}
+
+func TestCloseClient(t *testing.T) {
+	address, _ := standIn(t)
+
+	client, err := Dial(address, "DL1ABC")
+	require.NoError(t, err)
+	client.Close()
+
+	_, open := <-client.Spots()
+	assert.False(t, open, "spots should be closed")
+}
+
+func trim(spot core.Spot) core.Spot {
+	return core.Spot{Call: spot.Call, Frequency: spot.Frequency}
+}
@@ -0,0 +1,86 @@
+// Package cluster provides a client for DX cluster nodes that receives the spots of other stations.
+package cluster
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ftl/panacotta/core"
+)
+
+// spotExpression matches the spot lines of DXSpider and AR-Cluster nodes, e.g.
+// DX de DL1ABC:     14025.0  K1A          CW 599                         1234Z
+var spotExpression = regexp.MustCompile(`^DX DE ([A-Z0-9/\-#]+):?\s+([0-9]+\.[0-9]+)\s+([A-Z0-9/]+)\s+(.*?)\s*([0-9]{4})Z`)
+
+// The comments of skimmer spots contain the SNR and the speed of the signal, e.g. CW    22 dB  28 WPM  CQ
+var (
+	snrExpression = regexp.MustCompile(`(-?[0-9]+) DB\b`)
+	wpmExpression = regexp.MustCompile(`([0-9]+) WPM\b`)
+)
+
+// ParseSpot parses the given line from a DX cluster. The second return value is false if the line is not a spot.
+func ParseSpot(line string, now time.Time) (core.Spot, bool) {
+	match := spotExpression.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(line)))
+	if match == nil {
+		return core.Spot{}, false
+	}
+
+	kHz, err := strconv.ParseFloat(match[2], 64)
+	if err != nil {
+		return core.Spot{}, false
+	}
+	frequency := core.Frequency(kHz * 1000)
+	comment := strings.TrimSpace(match[4])
+
+	result := core.Spot{
+		Call:      match[3],
+		Frequency: frequency,
+		Mode:      modeOf(comment, frequency),
+		Spotter:   strings.TrimRight(match[1], "-#"),
+		Skimmer:   strings.HasSuffix(match[1], "-#"),
+		Comment:   comment,
+		Time:      spotTime(match[5], now),
+	}
+	if snr := snrExpression.FindStringSubmatch(comment); snr != nil {
+		value, _ := strconv.Atoi(snr[1])
+		result.SNR = core.DB(value)
+	}
+	if wpm := wpmExpression.FindStringSubmatch(comment); wpm != nil {
+		result.WPM, _ = strconv.Atoi(wpm[1])
+	}
+	return result, true
+}
+
+// modeOf returns the hamlib mode that is mentioned in the given comment, or the empty string if the comment does not
+// mention a known mode.
+func modeOf(comment string, frequency core.Frequency) string {
+	for _, word := range strings.Fields(comment) {
+		switch word {
+		case "CW", "RTTY", "USB", "LSB":
+			return word
+		case "SSB":
+			if frequency < 10000000 {
+				return "LSB"
+			}
+			return "USB"
+		case "FT8", "FT4", "PSK", "PSK31", "JT65":
+			return "PKTUSB"
+		}
+	}
+	return ""
+}
+
+// spotTime returns the time of the given HHMM timestamp on the day of the given time. Timestamps in the future are
+// taken from the day before.
+func spotTime(hhmm string, now time.Time) time.Time {
+	now = now.UTC()
+	hour, _ := strconv.Atoi(hhmm[:2])
+	minute, _ := strconv.Atoi(hhmm[2:])
+	result := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, time.UTC)
+	if result.After(now.Add(time.Minute)) {
+		result = result.AddDate(0, 0, -1)
+	}
+	return result
+}
@@ -0,0 +1,60 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ftl/panacotta/core"
+)
+
+func TestParseSpot(t *testing.T) {
+	now := time.Date(2020, 6, 6, 12, 40, 0, 0, time.UTC)
+	tt := []struct {
+		name     string
+		line     string
+		valid    bool
+		expected core.Spot
+	}{
+		{
+			name:     "DXSpider CW",
+			line:     "DX de DL1ABC:     14025.0  K1A          CW 599                         1234Z",
+			valid:    true,
+			expected: core.Spot{Call: "K1A", Frequency: 14025000, Mode: "CW", Spotter: "DL1ABC", Comment: "CW 599", Time: time.Date(2020, 6, 6, 12, 34, 0, 0, time.UTC)},
+		},
+		{
+			name:     "AR-Cluster SSB with locator",
+			line:     "DX de W3LPL:      7185.5  EA8XYZ       ssb tnx qso                    2359Z FN20",
+			valid:    true,
+			expected: core.Spot{Call: "EA8XYZ", Frequency: 7185500, Mode: "LSB", Spotter: "W3LPL", Comment: "SSB TNX QSO", Time: time.Date(2020, 6, 5, 23, 59, 0, 0, time.UTC)},
+		},
+		{
+			name:     "skimmer spot",
+			line:     "DX de OH6BG-#:    21074.0  PY2ABC       FT8 -12 dB                     1239Z",
+			valid:    true,
+			expected: core.Spot{Call: "PY2ABC", Frequency: 21074000, Mode: "PKTUSB", Spotter: "OH6BG", Skimmer: true, SNR: -12, Comment: "FT8 -12 DB", Time: time.Date(2020, 6, 6, 12, 39, 0, 0, time.UTC)},
+		},
+		{
+			name:     "RBN",
+			line:     "DX de EA5WU-#:    7023.5  DL1ABC       CW    22 dB  28 WPM  CQ      1234Z",
+			valid:    true,
+			expected: core.Spot{Call: "DL1ABC", Frequency: 7023500, Mode: "CW", Spotter: "EA5WU", Skimmer: true, SNR: 22, WPM: 28, Comment: "CW    22 DB  28 WPM  CQ", Time: time.Date(2020, 6, 6, 12, 34, 0, 0, time.UTC)},
+		},
+		{
+			name:     "no mode",
+			line:     "DX de DL1ABC:     14195.0  3Y0J                                       1234Z",
+			valid:    true,
+			expected: core.Spot{Call: "3Y0J", Frequency: 14195000, Spotter: "DL1ABC", Time: time.Date(2020, 6, 6, 12, 34, 0, 0, time.UTC)},
+		},
+		{name: "announcement", line: "To ALL de DL1ABC: hello"},
+		{name: "prompt", line: "DL1ABC de GB7DXC  6-Jun-2020 1240Z dxspider >"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			spot, valid := ParseSpot(tc.line, now)
+			assert.Equal(t, tc.valid, valid)
+			assert.Equal(t, tc.expected, spot)
+		})
+	}
+}
@@ -1,6 +1,9 @@
 package core
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/ftl/hamradio"
 	"github.com/ftl/hamradio/bandplan"
 )
@@ -38,11 +41,220 @@ type DBRange = hamradio.DBRange
 
 // Configuration parameters of the application.
 type Configuration struct {
+	DeviceIndex         int
+	DeviceSerial        string
 	FrequencyCorrection int
 	Testmode            bool
+	Testfile            string
+	TestfileLoop        bool
 	VFOHost             string
+	RTLTCPHost          string
+	RTLTCPServer        string
 	FFTPerSecond        int
+	FFTWindow           FFTWindow
+	FFTOverlap          float64 // overlap of consecutive FFT frames, 0.5 or 0.75; 0 processes only one block per FFT
+	Averaging           Averaging
+	PeakDetection       PeakDetection
+	PeakPresets         map[bandplan.BandName]PeakDetection // replace the peak detection parameters on specific bands
+	DSPWorkers          int                                 // number of workers that process FFT frames in parallel, 0 uses one worker per CPU
 	DynamicRange        DBRange
+
+	Rig           string
+	IFCenter      Frequency
+	SampleRate    int
+	BlockSize     int
+	RXOffset      Frequency // only used if FixedRXOffset is set, otherwise the offset is derived from the sample rate
+	FixedRXOffset bool
+
+	RecordingDirectory string
+	RecordingFormat    string
+
+	ActivityLog       bool
+	ActivityLogFormat string
+
+	CWSkimmer bool
+
+	IARURegion     int
+	SegmentWarning bool
+
+	FreezeOnTX bool
+
+	ClusterAddress  string
+	RBNAddress      string
+	ClusterCallsign string
+	SpotMaxAge      time.Duration
+
+	Tuner        TunerSettings
+	IQCorrection IQCorrection
+}
+
+// TunerSettings of the SDR dongle.
+type TunerSettings struct {
+	ManualGain bool
+	Gain       DB   // only effective with manual gain
+	AGC        bool // the digital AGC of the RTL2832
+	Bandwidth  int  // in Hz, 0 selects the bandwidth automatically
+}
+
+// IQCorrection configures the corrections that are applied to the incoming samples before the FFT.
+type IQCorrection struct {
+	SwapIQ           bool // exchange I and Q of each sample, for hardware that delivers Q first
+	InvertSpectrum   bool // mirror the spectrum at the center frequency
+	RemoveDC         bool // remove the DC offset, which otherwise shows up as spur in the center
+	CorrectImbalance bool // correct the gain and phase imbalance between I and Q
+}
+
+// WindowFunction is applied to the samples before the FFT.
+type WindowFunction int
+
+// All window functions.
+const (
+	WindowRectangular WindowFunction = iota
+	WindowHann
+	WindowBlackman
+	WindowBlackmanHarris
+	WindowFlatTop
+	WindowKaiser
+)
+
+var windowFunctionNames = map[WindowFunction]string{
+	WindowRectangular:    "rectangular",
+	WindowHann:           "hann",
+	WindowBlackman:       "blackman",
+	WindowBlackmanHarris: "blackman-harris",
+	WindowFlatTop:        "flat-top",
+	WindowKaiser:         "kaiser",
+}
+
+func (f WindowFunction) String() string {
+	name, ok := windowFunctionNames[f]
+	if !ok {
+		return "unknown"
+	}
+	return name
+}
+
+// Next returns the next window function in the order of the constants, after the last one the first one follows.
+func (f WindowFunction) Next() WindowFunction {
+	return (f + 1) % WindowFunction(len(windowFunctionNames))
+}
+
+// ParseWindowFunction returns the window function with the given name.
+func ParseWindowFunction(s string) (WindowFunction, bool) {
+	for f, name := range windowFunctionNames {
+		if name == s {
+			return f, true
+		}
+	}
+	return WindowRectangular, false
+}
+
+// FFTWindow selects the window that is applied to the samples before the FFT.
+type FFTWindow struct {
+	Function   WindowFunction
+	KaiserBeta float64 // only used by the Kaiser window
+}
+
+func (w FFTWindow) String() string {
+	if w.Function == WindowKaiser {
+		return fmt.Sprintf("%s β=%.1f", w.Function, w.KaiserBeta)
+	}
+	return w.Function.String()
+}
+
+// AveragingMode defines how the spectrum is averaged over time.
+type AveragingMode int
+
+// All averaging modes.
+const (
+	AveragingNone AveragingMode = iota
+	AveragingMoving
+	AveragingExponential
+	AveragingMaxHold
+	AveragingMinHold
+)
+
+var averagingModeNames = map[AveragingMode]string{
+	AveragingNone:        "none",
+	AveragingMoving:      "moving",
+	AveragingExponential: "exponential",
+	AveragingMaxHold:     "max-hold",
+	AveragingMinHold:     "min-hold",
+}
+
+func (m AveragingMode) String() string {
+	name, ok := averagingModeNames[m]
+	if !ok {
+		return "unknown"
+	}
+	return name
+}
+
+// Next returns the next averaging mode in the order of the constants, after the last one the first one follows.
+func (m AveragingMode) Next() AveragingMode {
+	return (m + 1) % AveragingMode(len(averagingModeNames))
+}
+
+// ParseAveragingMode returns the averaging mode with the given name.
+func ParseAveragingMode(s string) (AveragingMode, bool) {
+	for m, name := range averagingModeNames {
+		if name == s {
+			return m, true
+		}
+	}
+	return AveragingNone, false
+}
+
+// Averaging of the spectrum over time. In the hold modes, the live spectrum is not averaged and the hold values are
+// provided as separate trace.
+type Averaging struct {
+	Mode  AveragingMode
+	Depth int     // number of spectra for the moving average
+	Alpha float64 // weight of the latest spectrum for the exponential average
+}
+
+func (a Averaging) String() string {
+	switch a.Mode {
+	case AveragingMoving:
+		return fmt.Sprintf("%s N=%d", a.Mode, a.Depth)
+	case AveragingExponential:
+		return fmt.Sprintf("%s α=%.2f", a.Mode, a.Alpha)
+	default:
+		return a.Mode.String()
+	}
+}
+
+// PeakDetection contains the parameters that control the sensitivity of the peak detection.
+type PeakDetection struct {
+	WindowSize      int           // number of bins of the sliding window for the sigma envelope, must be odd
+	ThresholdOffset float64       // rise of the sigma envelope in dB that starts a peak
+	MinWidth        Frequency     // narrower peaks are ignored
+	Persistence     time.Duration // a peak is shown this long after it was detected for the last time
+}
+
+// Normalized returns a copy of these parameters with a valid window size.
+func (p PeakDetection) Normalized() PeakDetection {
+	if p.WindowSize < 1 {
+		p.WindowSize = 1
+	}
+	if p.WindowSize%2 == 0 {
+		p.WindowSize++
+	}
+	return p
+}
+
+// EffectiveRXOffset returns the offset of the SDR receiver from the IF center. Unless the offset is fixed, the receiver
+// is tuned a quarter of the sample rate below the IF center to keep the DC spike away from the IF.
+func (c Configuration) EffectiveRXOffset() Frequency {
+	if c.FixedRXOffset {
+		return c.RXOffset
+	}
+	return -Frequency(c.SampleRate) / 4
+}
+
+// RXCenter returns the frequency the SDR receiver is tuned to.
+func (c Configuration) RXCenter() Frequency {
+	return c.IFCenter + c.EffectiveRXOffset()
 }
 
 // ViewMode of the panorama.
@@ -60,6 +272,51 @@ type SamplesInput interface {
 	Close() error
 }
 
+// BlockReleaser is implemented by a SamplesInput that reuses its blocks of samples. Every block received from such
+// an input must be released as soon as it is not used anymore.
+type BlockReleaser interface {
+	Release([]complex128)
+}
+
+// ClippingMeter is implemented by a SamplesInput that measures the clipping of the ADC on its raw samples. Clipping
+// returns the ratio of clipped samples in the given block, which must be received from this input and not yet released.
+type ClippingMeter interface {
+	Clipping([]complex128) float64
+}
+
+// TunerControl is implemented by a SamplesInput that allows to change the tuner settings at runtime.
+type TunerControl interface {
+	SetTunerSettings(TunerSettings) error
+}
+
+// InputState describes the state of the samples input.
+type InputState int
+
+// All input states.
+const (
+	InputRunning InputState = iota
+	InputStalled
+	InputReconnecting
+)
+
+func (s InputState) String() string {
+	switch s {
+	case InputRunning:
+		return "running"
+	case InputStalled:
+		return "stalled"
+	case InputReconnecting:
+		return "reconnecting"
+	default:
+		return "unknown"
+	}
+}
+
+// InputStateReporter is implemented by a SamplesInput that reports changes of its state.
+type InputStateReporter interface {
+	InputState() <-chan InputState
+}
+
 // Frct is a fraction of height or width; this is a abstraction of the coordinates on the screen.
 type Frct float64
 
@@ -102,12 +359,109 @@ type DBMark struct {
 
 // PeakMark contains all information to visualize a peak
 type PeakMark struct {
-	FromX        Frct
-	ToX          Frct
-	MaxX         Frct
-	MaxFrequency Frequency
-	ValueY       Frct
-	ValueDB      DB
+	FromX         Frct
+	ToX           Frct
+	MaxX          Frct
+	MaxFrequency  Frequency
+	ValueY        Frct
+	ValueDB       DB
+	SNR           DB
+	Class         SignalClass
+	TuneFrequency Frequency // the VFO frequency to receive the signal of this peak
+	CWText        string    // the text decoded by the CW skimmer, if any
+	Callsign      string    // the last callsign in CWText
+}
+
+// SignalActivity describes a signal that was detected in the spectrum over a period of time.
+type SignalActivity struct {
+	FirstSeen time.Time
+	LastSeen  time.Time
+	Frequency Frequency // center frequency
+	Bandwidth Frequency
+	MaxLevel  DB
+	Band      bandplan.BandName
+	Class     SignalClass
+}
+
+// CWText is the text that the CW skimmer decoded on a frequency.
+type CWText struct {
+	Frequency Frequency
+	Text      string
+	Callsign  string // the last callsign in the text
+	WPM       int
+}
+
+// Spot is a station that was reported on a DX cluster.
+type Spot struct {
+	Call      string
+	Frequency Frequency
+	Mode      string // hamlib mode, empty if unknown
+	Spotter   string
+	Skimmer   bool // the spot was reported by a skimmer, e.g. from the Reverse Beacon Network
+	SNR       DB   // only reported by skimmers
+	WPM       int  // only reported by skimmers
+	Comment   string
+	Time      time.Time
+}
+
+// SpotMark is a spot within the panorama.
+type SpotMark struct {
+	X         Frct
+	Frequency Frequency
+	Call      string
+	Mode      string
+}
+
+// SkimmerSpotMark is a station within the panorama that was reported by one or more skimmers.
+type SkimmerSpotMark struct {
+	X         Frct
+	Frequency Frequency
+	Call      string
+	SNR       DB // the best SNR of all reports
+	WPM       int
+	Spotters  int
+}
+
+// Bookmark is a stored frequency, like a memory channel of a transceiver.
+type Bookmark struct {
+	Frequency Frequency `json:"frequency"`
+	Mode      string    `json:"mode,omitempty"` // hamlib mode, empty to keep the current mode
+	Label     string    `json:"label"`
+	Color     string    `json:"color,omitempty"` // optional, #rrggbb
+}
+
+// BookmarkMark is a bookmark within the panorama.
+type BookmarkMark struct {
+	X Frct
+	Bookmark
+}
+
+// SignalClass is the kind of signal that was detected in a peak.
+type SignalClass int
+
+// All signal classes.
+const (
+	SignalUnknown SignalClass = iota
+	SignalCW
+	SignalSSB
+	SignalFT8 // FT8 or FT4 slot activity
+	SignalRTTY
+)
+
+var signalClassNames = map[SignalClass]string{
+	SignalUnknown: "unknown",
+	SignalCW:      "CW",
+	SignalSSB:     "SSB",
+	SignalFT8:     "FT8/FT4",
+	SignalRTTY:    "RTTY",
+}
+
+func (c SignalClass) String() string {
+	name, ok := signalClassNames[c]
+	if !ok {
+		return signalClassNames[SignalUnknown]
+	}
+	return name
 }
 
 // Px unit for pixels
@@ -133,18 +487,33 @@ type Panorama struct {
 	Band           bandplan.Band
 	Resolution     HzPerPx
 
-	VFOLine        Frct
-	VFOFilterFrom  Frct
-	VFOFilterTo    Frct
-	VFOSignalLevel DB
+	VFOLine         Frct
+	VFOFilterFrom   Frct
+	VFOFilterTo     Frct
+	VFOSignalLevel  DB
+	VFOOutOfSegment bool // the VFO is outside of the bandplan segment for its mode
+
+	TXVFOLine       Frct // only valid if VFO.TX.Frequency is known
+	TXVFOFilterFrom Frct
+	TXVFOFilterTo   Frct
 
 	FrequencyScale     []FrequencyMark
 	DBScale            []DBMark
 	Spectrum           []FPoint
 	PeakThresholdLevel Frct
+	NoiseFloorLevel    Frct
 	SigmaEnvelope      []FPoint
 	Peaks              []PeakMark
+	Spots              []SpotMark
+	SkimmerSpots       []SkimmerSpotMark
+	Bookmarks          []BookmarkMark
 	Waterline          []Frct
+	Overload           bool
+	InputState         InputState
+	FFTWindow          FFTWindow
+	Averaging          Averaging
+	HoldSpectrum       []FPoint
+	Frozen             bool // the spectrum is frozen while transmitting
 }
 
 // ToPx converts the given frequency in Hz to Px within the panorama.
@@ -163,6 +532,18 @@ type VFO struct {
 	Frequency   Frequency
 	FilterWidth Frequency
 	Mode        string
+
+	Split bool // transmit on the TX VFO
+	PTT   bool // the transceiver is transmitting
+	TX    TXVFO
+}
+
+// TXVFO is the second VFO of the transceiver that is used for transmitting in split operation, usually VFO B.
+type TXVFO struct {
+	Name        string
+	Frequency   Frequency
+	FilterWidth Frequency
+	Mode        string
 }
 
 // FFT data and the corresponding frequency range
@@ -170,9 +551,14 @@ type FFT struct {
 	Data          []float64
 	Range         FrequencyRange
 	Mean          float64
+	NoiseFloor    float64 // robust estimate of the noise level, strong signals are excluded
 	PeakThreshold float64
 	SigmaEnvelope []float64
 	Peaks         []PeakIndexRange
+	Clipping      float64 // ratio of input samples at the limits of the ADC range
+	Window        FFTWindow
+	Averaging     Averaging
+	Hold          []float64 // the max-hold or min-hold trace, only in the hold averaging modes
 }
 
 // Resolution of this FFT in Hz per Bin
@@ -196,4 +582,5 @@ type PeakIndexRange struct {
 	To    int
 	Max   int
 	Value float64
+	SNR   float64 // distance of the value to the noise floor in dB
 }
@@ -21,7 +21,7 @@ func TestDBRange_Width(t *testing.T) {
 
 	for i, tc := range tt {
 		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
-			actual := DBRange{tc.from, tc.to}.Width()
+			actual := DBRange{From: tc.from, To: tc.to}.Width()
 			assert.Equal(t, tc.expected, actual)
 		})
 	}
@@ -44,8 +44,60 @@ func TestDBRange_ToFrct(t *testing.T) {
 
 	for i, tc := range tt {
 		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
-			actual := ToDBFrct(tc.value, DBRange{tc.from, tc.to})
+			actual := ToDBFrct(tc.value, DBRange{From: tc.from, To: tc.to})
 			assert.Equal(t, tc.expected, actual)
 		})
 	}
 }
+
+func TestWindowFunction(t *testing.T) {
+	for f := WindowRectangular; f <= WindowKaiser; f++ {
+		parsed, ok := ParseWindowFunction(f.String())
+		assert.True(t, ok)
+		assert.Equal(t, f, parsed)
+	}
+
+	_, ok := ParseWindowFunction("hamming")
+	assert.False(t, ok)
+
+	assert.Equal(t, WindowHann, WindowRectangular.Next())
+	assert.Equal(t, WindowRectangular, WindowKaiser.Next())
+	assert.Equal(t, "kaiser β=8.6", FFTWindow{Function: WindowKaiser, KaiserBeta: 8.6}.String())
+}
+
+func TestAveragingMode(t *testing.T) {
+	for m := AveragingNone; m <= AveragingMinHold; m++ {
+		parsed, ok := ParseAveragingMode(m.String())
+		assert.True(t, ok)
+		assert.Equal(t, m, parsed)
+	}
+
+	assert.Equal(t, AveragingNone, AveragingMinHold.Next())
+	assert.Equal(t, "moving N=5", Averaging{Mode: AveragingMoving, Depth: 5}.String())
+	assert.Equal(t, "exponential α=0.30", Averaging{Mode: AveragingExponential, Alpha: 0.3}.String())
+}
+
+func TestPeakDetection_Normalized(t *testing.T) {
+	assert.Equal(t, 9, PeakDetection{WindowSize: 9}.Normalized().WindowSize)
+	assert.Equal(t, 11, PeakDetection{WindowSize: 10}.Normalized().WindowSize)
+	assert.Equal(t, 1, PeakDetection{WindowSize: 0}.Normalized().WindowSize)
+	assert.Equal(t, 1, PeakDetection{WindowSize: -4}.Normalized().WindowSize)
+}
+
+func TestSignalClass(t *testing.T) {
+	assert.Equal(t, "FT8/FT4", SignalFT8.String())
+	assert.Equal(t, "unknown", SignalClass(42).String())
+}
+
+func TestConfiguration_RXCenter(t *testing.T) {
+	c := Configuration{IFCenter: 67899000, SampleRate: 1800000}
+	assert.Equal(t, Frequency(-450000), c.EffectiveRXOffset())
+	assert.Equal(t, Frequency(67449000), c.RXCenter())
+
+	c.SampleRate = 2400000
+	assert.Equal(t, Frequency(67299000), c.RXCenter())
+
+	c.RXOffset = 300000
+	c.FixedRXOffset = true
+	assert.Equal(t, Frequency(68199000), c.RXCenter())
+}
@@ -0,0 +1,178 @@
+package cw
+
+import (
+	"math"
+	"strings"
+)
+
+const (
+	initialWPM     = 20
+	minMarkLength  = 0.01 // seconds, shorter marks are ignored
+	levelTracking  = 2.0  // seconds, time constant of the signal and noise level trackers
+	timingAdaption = 0.2
+	fastMarkRatio  = 0.75 // a mark shorter than this fraction of a dit resets the timing
+	minKeyingRatio = 2.0  // the signal level must be at least this factor above the noise level
+	maxTextLength  = 64
+)
+
+// NewDecoder returns a new decoder for an envelope with the given number of samples per second.
+func NewDecoder(rate float64) *Decoder {
+	dit := rate * ditSeconds(initialWPM)
+	return &Decoder{
+		rate:          rate,
+		levelTracking: 1 - math.Exp(-1/(rate*levelTracking)),
+		minMark:       int(rate * minMarkLength),
+		dit:           dit,
+		dah:           3 * dit,
+		charDone:      true,
+		wordDone:      true,
+	}
+}
+
+// Decoder decodes Morse code from the envelope of a CW signal. The threshold between key up and key down adapts to the
+// signal and noise levels, the timing adapts to the speed of the signal.
+type Decoder struct {
+	rate          float64
+	levelTracking float64
+	minMark       int
+
+	initialized bool
+	signal      float64
+	noise       float64
+	keyDown     bool
+	duration    int // number of samples in the current key state
+
+	dit      float64 // estimated length of a dit in samples
+	dah      float64 // estimated length of a dah in samples
+	symbol   []byte
+	charDone bool
+	wordDone bool
+	text     []rune
+}
+
+func ditSeconds(wpm float64) float64 {
+	return 1.2 / wpm
+}
+
+// Put the next sample of the envelope into the decoder.
+func (d *Decoder) Put(v float64) {
+	if !d.initialized {
+		d.signal = v
+		d.noise = v
+		d.initialized = true
+	}
+	d.trackLevels(v)
+
+	keyDown := d.keyed(v)
+	if keyDown == d.keyDown {
+		d.duration++
+		if !keyDown {
+			d.space(d.duration)
+		}
+		return
+	}
+
+	if d.keyDown {
+		d.mark(d.duration)
+	}
+	d.keyDown = keyDown
+	d.duration = 1
+}
+
+// trackLevels follows the maximum and the minimum of the envelope, both relax slowly towards the current value.
+func (d *Decoder) trackLevels(v float64) {
+	if v > d.signal {
+		d.signal = v
+	} else {
+		d.signal += (v - d.signal) * d.levelTracking
+	}
+	if v < d.noise {
+		d.noise = v
+	} else {
+		d.noise += (v - d.noise) * d.levelTracking
+	}
+}
+
+// keyed decides with some hysteresis if the key is down.
+func (d *Decoder) keyed(v float64) bool {
+	if d.signal < minKeyingRatio*d.noise {
+		return false
+	}
+	span := d.signal - d.noise
+	if d.keyDown {
+		return v > d.noise+0.4*span
+	}
+	return v > d.noise+0.6*span
+}
+
+func (d *Decoder) mark(length int) {
+	if length < d.minMark {
+		return
+	}
+	l := float64(length)
+
+	if l < fastMarkRatio*d.dit {
+		// a mark that is much shorter than a dit can only be a dit, the speed increased significantly
+		d.dit = l
+		d.dah = 3 * l
+	}
+
+	if l < (d.dit+d.dah)/2 {
+		d.symbol = append(d.symbol, '.')
+		d.dit += (l - d.dit) * timingAdaption
+		d.dah = math.Min(math.Max(d.dah, 2*d.dit), 4*d.dit)
+	} else {
+		d.symbol = append(d.symbol, '-')
+		d.dah += (l - d.dah) * timingAdaption
+		d.dit = math.Min(math.Max(d.dit, d.dah/4), d.dah/2)
+	}
+	if len(d.symbol) > longestSymbol {
+		d.symbol = d.symbol[:0]
+	}
+	d.charDone = false
+	d.wordDone = false
+}
+
+func (d *Decoder) space(length int) {
+	l := float64(length)
+	if !d.charDone && l > 2*d.dit {
+		d.charDone = true
+		if r, ok := morseSymbols[string(d.symbol)]; ok {
+			d.appendText(r)
+		}
+		d.symbol = d.symbol[:0]
+	}
+	if !d.wordDone && l > 5*d.dit {
+		d.wordDone = true
+		if len(d.text) > 0 && d.text[len(d.text)-1] != ' ' {
+			d.appendText(' ')
+		}
+	}
+}
+
+func (d *Decoder) appendText(r rune) {
+	d.text = append(d.text, r)
+	if len(d.text) > maxTextLength {
+		d.text = d.text[len(d.text)-maxTextLength:]
+	}
+}
+
+// Text returns the text that was decoded so far.
+func (d *Decoder) Text() string {
+	return strings.TrimSpace(string(d.text))
+}
+
+// completeWords returns the words of the decoded text that are already followed by a word space.
+func (d *Decoder) completeWords() string {
+	text := string(d.text)
+	end := strings.LastIndex(text, " ")
+	if end < 0 {
+		return ""
+	}
+	return strings.TrimSpace(text[:end])
+}
+
+// WPM returns the estimated speed in words per minute.
+func (d *Decoder) WPM() int {
+	return int(math.Round(ditSeconds(1) * d.rate / d.dit))
+}
@@ -0,0 +1,79 @@
+package cw
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// envelope returns the envelope of the given text, keyed with the given speed and sampled at the given rate.
+func envelope(text string, wpm float64, rate float64, noise float64) []float64 {
+	random := rand.New(rand.NewSource(1))
+	ditLength := int(rate * ditSeconds(wpm))
+	keying := newKeying(text)
+	result := make([]float64, 0, len(keying)*ditLength)
+	for _, keyDown := range keying {
+		for i := 0; i < ditLength; i++ {
+			v := random.Float64() * noise
+			if keyDown {
+				v += 1
+			}
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+func TestDecoder(t *testing.T) {
+	tt := []struct {
+		name  string
+		wpm   float64
+		noise float64
+	}{
+		{"20 WPM", 20, 0},
+		{"12 WPM", 12, 0},
+		{"35 WPM", 35, 0},
+		{"noisy", 25, 0.2},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			decoder := NewDecoder(1000)
+			for _, v := range envelope("CQ TEST DL1ABC DL1ABC TEST", tc.wpm, 1000, tc.noise) {
+				decoder.Put(v)
+			}
+
+			assert.Contains(t, decoder.Text(), "DL1ABC DL1ABC TEST")
+			assert.Equal(t, decoder.Text(), decoder.completeWords())
+			assert.InDelta(t, tc.wpm, decoder.WPM(), tc.wpm*0.1)
+		})
+	}
+}
+
+func TestDecoderAdaptsToSpeedChange(t *testing.T) {
+	decoder := NewDecoder(1000)
+	for _, v := range envelope("CQ DL1ABC", 18, 1000, 0) {
+		decoder.Put(v)
+	}
+	for _, v := range envelope("TU 5NN K1A K1A", 30, 1000, 0) {
+		decoder.Put(v)
+	}
+
+	assert.Contains(t, decoder.Text(), "K1A K1A")
+	for _, v := range envelope("TU", 30, 1000, 0)[:100] {
+		decoder.Put(v)
+	}
+	assert.True(t, strings.HasSuffix(decoder.completeWords(), "K1A K1A"), "incomplete words are not included")
+	assert.InDelta(t, 30, decoder.WPM(), 3)
+}
+
+func TestDecoderIgnoresNoise(t *testing.T) {
+	decoder := NewDecoder(1000)
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 10000; i++ {
+		decoder.Put(0.5 + random.Float64()*0.1)
+	}
+
+	assert.Equal(t, "", decoder.Text())
+}
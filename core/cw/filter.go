@@ -0,0 +1,89 @@
+package cw
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// decimatorTaps is the number of filter taps per decimation step. With a Blackman window, the transition band of the
+// low-pass is about a third of the output rate wide, so the aliases stay outside of the inner two thirds.
+const decimatorTaps = 16
+
+// lowpass returns the coefficients of a Blackman windowed sinc low-pass filter with the given number of taps and the
+// given cutoff frequency as ratio of the sample rate. The gain at DC is 1.
+func lowpass(taps int, cutoff float64) []float64 {
+	result := make([]float64, taps)
+	center := float64(taps-1) / 2
+	sum := 0.0
+	for i := range result {
+		x := float64(i) - center
+		var h float64
+		if x == 0 {
+			h = 2 * cutoff
+		} else {
+			h = math.Sin(2*math.Pi*cutoff*x) / (math.Pi * x)
+		}
+		φ := 2 * math.Pi * float64(i) / float64(taps-1)
+		result[i] = h * (0.42 - 0.5*math.Cos(φ) + 0.08*math.Cos(2*φ))
+		sum += result[i]
+	}
+	for i := range result {
+		result[i] /= sum
+	}
+	return result
+}
+
+func newDecimator(factor int) *decimator {
+	result := &decimator{
+		factor:     factor,
+		oscillator: 1,
+	}
+	if factor > 1 {
+		result.coefficients = lowpass(decimatorTaps*factor+1, 0.5/float64(factor))
+	}
+	return result
+}
+
+// decimator mixes a stream of samples down to baseband, low-pass filters it and keeps only every n-th sample. The
+// state is kept between blocks, so that the stream is processed without gaps.
+type decimator struct {
+	factor       int
+	coefficients []float64
+	oscillator   complex128
+	history      []complex128 // mixed samples that are not completely consumed by the filter yet
+	output       []complex128
+}
+
+// process the given block of samples, mixed down by the given rate. The returned samples are only valid until the next
+// call of process.
+func (d *decimator) process(block []complex128, rate float64) []complex128 {
+	ω := -2 * math.Pi * rate
+	step := complex(math.Cos(ω), math.Sin(ω))
+	for _, s := range block {
+		d.history = append(d.history, s*d.oscillator)
+		d.oscillator *= step
+	}
+	d.oscillator /= complex(cmplx.Abs(d.oscillator), 0)
+
+	d.output = d.output[:0]
+	if d.factor == 1 {
+		d.output = append(d.output, d.history...)
+		d.history = d.history[:0]
+		return d.output
+	}
+
+	taps := len(d.coefficients)
+	i := 0
+	for ; i+taps <= len(d.history); i += d.factor {
+		var re, im float64
+		for k, c := range d.coefficients {
+			s := d.history[i+k]
+			re += real(s) * c
+			im += imag(s) * c
+		}
+		d.output = append(d.output, complex(re, im))
+	}
+	remaining := copy(d.history, d.history[i:])
+	d.history = d.history[:remaining]
+	return d.output
+}
@@ -0,0 +1,50 @@
+package cw
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLowpass(t *testing.T) {
+	coefficients := lowpass(41, 0.1)
+
+	sum := 0.0
+	for _, c := range coefficients {
+		sum += c
+	}
+	assert.InDelta(t, 1.0, sum, 1e-9)
+	assert.Equal(t, coefficients[0], coefficients[40], "the filter is symmetric")
+}
+
+func TestDecimator(t *testing.T) {
+	tt := []struct {
+		name      string
+		rate      float64
+		minGainDB float64
+		maxGainDB float64
+	}{
+		{"passband", 0.01, -0.1, 0.1},
+		{"alias", 0.1 + 0.01, math.Inf(-1), -60},
+		{"stopband", 0.3, math.Inf(-1), -60},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			d := newDecimator(10)
+			block := make([]complex128, 1000)
+			var output []complex128
+			for i := 0; i < 4; i++ {
+				for j := range block {
+					block[j] = cmplx.Exp(complex(0, 2*math.Pi*tc.rate*float64(i*len(block)+j)))
+				}
+				output = d.process(block, 0)
+			}
+
+			assert.Equal(t, 100, len(output))
+			gain := 20 * math.Log10(cmplx.Abs(output[len(output)-1]))
+			assert.True(t, tc.minGainDB <= gain && gain <= tc.maxGainDB, "gain %.1fdB", gain)
+		})
+	}
+}
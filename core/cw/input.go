@@ -0,0 +1,103 @@
+package cw
+
+import (
+	"log"
+	"math"
+	"time"
+)
+
+// newKeying returns the key states of the given text, one state per dit length. The text is followed by a word space,
+// so that it can be repeated.
+func newKeying(text string) []bool {
+	result := make([]bool, 0, 8*len(text))
+	for _, element := range Encode(text) {
+		switch element {
+		case '.':
+			result = append(result, true, false)
+		case '-':
+			result = append(result, true, true, true, false)
+		case ' ':
+			result = append(result, false, false)
+		}
+	}
+	// pad the trailing element space to a word space
+	return append(result, false, false, false, false, false, false)
+}
+
+// keyedTone generates a tone that is keyed with the Morse code of a text.
+type keyedTone struct {
+	keying    []bool
+	ditLength int // samples
+	step      complex128
+	phase     complex128
+	amplitude float64
+	sample    int
+}
+
+func newKeyedTone(sampleRate int, f float64, wpm float64, text string) *keyedTone {
+	ω := 2.0 * math.Pi * f / float64(sampleRate)
+	return &keyedTone{
+		keying:    newKeying(text),
+		ditLength: int(float64(sampleRate) * ditSeconds(wpm)),
+		step:      complex(math.Cos(ω), math.Sin(ω)),
+		phase:     1,
+		amplitude: 1,
+	}
+}
+
+// Read fills the given block with the next samples of the keyed tone.
+func (t *keyedTone) Read(block []complex128) {
+	for i := range block {
+		element := (t.sample / t.ditLength) % len(t.keying)
+		if t.keying[element] {
+			block[i] = t.phase * complex(t.amplitude, 0)
+		} else {
+			block[i] = 0
+		}
+		t.phase *= t.step
+		t.sample++
+	}
+	t.phase /= complex(math.Hypot(real(t.phase), imag(t.phase)), 0)
+}
+
+// NewKeyedToneInput returns a new SamplesInput that produces a tone with the given frequency, keyed with the Morse
+// code of the given text. The text is repeated endlessly.
+func NewKeyedToneInput(blockSize int, sampleRate int, f float64, wpm float64, text string) *KeyedToneInput {
+	result := KeyedToneInput{
+		samples: make(chan []complex128, 1),
+		done:    make(chan struct{}),
+	}
+	tone := newKeyedTone(sampleRate, f, wpm, text)
+
+	go func() {
+		defer log.Print("KeyedToneInput shutdown")
+		for {
+			nextBlock := make([]complex128, blockSize)
+			tone.Read(nextBlock)
+			select {
+			case result.samples <- nextBlock:
+				time.Sleep(time.Duration(float64(blockSize)/float64(sampleRate)*1000.0) * time.Millisecond)
+			case <-result.done:
+				close(result.samples)
+				return
+			}
+		}
+	}()
+
+	return &result
+}
+
+// KeyedToneInput is a SamplesInput that produces a tone, keyed with Morse code.
+type KeyedToneInput struct {
+	samples chan []complex128
+	done    chan struct{}
+}
+
+func (i *KeyedToneInput) Samples() <-chan []complex128 {
+	return i.samples
+}
+
+func (i *KeyedToneInput) Close() error {
+	close(i.done)
+	return nil
+}
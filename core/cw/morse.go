@@ -0,0 +1,58 @@
+// Package cw provides a skimmer that decodes Morse code from the narrow carriers in the IQ stream.
+package cw
+
+import (
+	"regexp"
+	"strings"
+)
+
+var morseCode = map[rune]string{
+	'A': ".-", 'B': "-...", 'C': "-.-.", 'D': "-..", 'E': ".", 'F': "..-.", 'G': "--.", 'H': "....", 'I': "..",
+	'J': ".---", 'K': "-.-", 'L': ".-..", 'M': "--", 'N': "-.", 'O': "---", 'P': ".--.", 'Q': "--.-", 'R': ".-.",
+	'S': "...", 'T': "-", 'U': "..-", 'V': "...-", 'W': ".--", 'X': "-..-", 'Y': "-.--", 'Z': "--..",
+	'0': "-----", '1': ".----", '2': "..---", '3': "...--", '4': "....-", '5': ".....", '6': "-....", '7': "--...",
+	'8': "---..", '9': "----.",
+	'/': "-..-.", '?': "..--..", '=': "-...-", '.': ".-.-.-", ',': "--..--",
+}
+
+var morseSymbols = func() map[string]rune {
+	result := make(map[string]rune, len(morseCode))
+	for r, code := range morseCode {
+		result[code] = r
+	}
+	return result
+}()
+
+// longestSymbol is the number of elements of the longest known symbol.
+const longestSymbol = 6
+
+// Encode returns the Morse code of the given text. The elements of a character are not separated, characters are
+// separated by one space, words by three spaces. Unknown characters are ignored.
+func Encode(text string) string {
+	words := strings.Fields(strings.ToUpper(text))
+	encodedWords := make([]string, 0, len(words))
+	for _, word := range words {
+		encodedChars := make([]string, 0, len(word))
+		for _, r := range word {
+			code, ok := morseCode[r]
+			if ok {
+				encodedChars = append(encodedChars, code)
+			}
+		}
+		encodedWords = append(encodedWords, strings.Join(encodedChars, " "))
+	}
+	return strings.Join(encodedWords, "   ")
+}
+
+var callsignExpression = regexp.MustCompile(`^([A-Z0-9]{1,3}/)?[A-Z0-9]{0,2}[A-Z][0-9]{1,2}[A-Z]{1,4}(/[A-Z0-9]{1,4})?$`)
+
+// Callsign returns the last callsign-like word of the given text, or the empty string if there is none.
+func Callsign(text string) string {
+	words := strings.Fields(text)
+	for i := len(words) - 1; i >= 0; i-- {
+		if callsignExpression.MatchString(words[i]) {
+			return words[i]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,40 @@
+package cw
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncode(t *testing.T) {
+	assert.Equal(t, "-.-. --.-   -.. .-.. .---- .- -... -.-.", Encode("cq dl1abc"))
+	assert.Equal(t, "", Encode(""))
+}
+
+func TestMorseSymbols(t *testing.T) {
+	for r, code := range morseCode {
+		assert.Equal(t, r, morseSymbols[code], code)
+		assert.True(t, len(code) <= longestSymbol, code)
+	}
+}
+
+func TestCallsign(t *testing.T) {
+	tt := []struct {
+		text     string
+		expected string
+	}{
+		{"", ""},
+		{"CQ TEST", ""},
+		{"CQ TEST DL1ABC DL1ABC TEST", "DL1ABC"},
+		{"TU 5NN 599", ""},
+		{"K1A TU DL1ABC 5NN", "DL1ABC"},
+		{"CQ 9A1A", "9A1A"},
+		{"DE 2E0XYZ/P K", "2E0XYZ/P"},
+		{"EA8/DL1ABC", "EA8/DL1ABC"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.text, func(t *testing.T) {
+			assert.Equal(t, tc.expected, Callsign(tc.text))
+		})
+	}
+}
@@ -0,0 +1,287 @@
+package cw
+
+import (
+	"log"
+	"math"
+	"time"
+
+	"github.com/ftl/panacotta/core"
+)
+
+const (
+	// MaxCarrierWidth is the width of the widest peak that is decoded as CW carrier.
+	MaxCarrierWidth = core.Frequency(150)
+
+	channelRate       = 4000                // samples per second after decimation
+	channelCutoff     = 150.0               // Hz, cutoff of the channel filter
+	channelFilterTaps = 41                  // about 10ms at the channel rate
+	channelMargin     = core.Frequency(500) // space around the outermost channels within the first stage
+	stagePassband     = 0.6                 // usable part of the first stage's bandwidth
+	carrierTolerance  = core.Frequency(40)
+	channelTimeout    = 15 * time.Second
+	maxChannels       = 32
+	skimmerBufferSize = 8
+)
+
+// Carriers returns the frequencies of the narrow peaks in the given FFT.
+func Carriers(fft core.FFT) []core.Frequency {
+	result := make([]core.Frequency, 0, len(fft.Peaks))
+	for _, peak := range fft.Peaks {
+		if fft.Frequency(peak.To)-fft.Frequency(peak.From) > MaxCarrierWidth {
+			continue
+		}
+		result = append(result, fft.Frequency(peak.Max))
+	}
+	return result
+}
+
+// NewSkimmer returns a new CW skimmer for the IQ stream with the given parameters.
+func NewSkimmer(sampleRate int, ifCenter, rxOffset core.Frequency) *Skimmer {
+	decimation := sampleRate / channelRate
+	if decimation < 1 {
+		decimation = 1
+	}
+	return &Skimmer{
+		blocks:  make(chan []complex128, skimmerBufferSize),
+		free:    make(chan []complex128, skimmerBufferSize),
+		command: make(chan func(), 4),
+		texts:   make(chan core.CWText, maxChannels),
+
+		sampleRate: sampleRate,
+		ifCenter:   ifCenter,
+		rxCenter:   ifCenter + rxOffset,
+		decimation: decimation,
+		channels:   make(map[core.Frequency]*channel),
+	}
+}
+
+// Skimmer decodes CW on all narrow carriers in the IQ stream in parallel. The stream is first decimated once to the
+// range that contains all carriers. Then each carrier is mixed down, decimated and filtered into its own narrow
+// channel with its own decoder.
+type Skimmer struct {
+	blocks  chan []complex128
+	free    chan []complex128
+	command chan func()
+	texts   chan core.CWText
+
+	sampleRate int
+	ifCenter   core.Frequency
+	rxCenter   core.Frequency
+	decimation int // from the sample rate to the channel rate
+
+	stage       *decimator // the first decimation stage, shared by all channels
+	stageCenter core.Frequency
+
+	vfo      core.VFO
+	channels map[core.Frequency]*channel
+}
+
+// Run the skimmer until the given stop channel is closed.
+func (s *Skimmer) Run(stop chan struct{}) {
+	defer log.Print("CW skimmer shutdown")
+	for {
+		select {
+		case block := <-s.blocks:
+			s.process(block)
+			select {
+			case s.free <- block:
+			default:
+			}
+		case cmd := <-s.command:
+			cmd()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Texts returns the channel that provides the decoded texts.
+func (s *Skimmer) Texts() <-chan core.CWText {
+	return s.texts
+}
+
+// Write the given block of samples. The samples are copied, the caller may reuse the given block afterwards.
+func (s *Skimmer) Write(block []complex128) {
+	var buffer []complex128
+	select {
+	case buffer = <-s.free:
+	default:
+	}
+	if cap(buffer) < len(block) {
+		buffer = make([]complex128, len(block))
+	}
+	buffer = buffer[:len(block)]
+	copy(buffer, block)
+
+	select {
+	case s.blocks <- buffer:
+	default:
+		log.Print("CW skimmer hangs")
+	}
+}
+
+// SetCarriers sets the frequencies of the carriers that are currently visible. New carriers get their own channel,
+// channels without a carrier for some time are closed.
+func (s *Skimmer) SetCarriers(vfo core.VFO, carriers []core.Frequency) {
+	s.q(func() {
+		s.setCarriers(vfo, carriers, time.Now())
+	})
+}
+
+func (s *Skimmer) setCarriers(vfo core.VFO, carriers []core.Frequency, now time.Time) {
+	s.vfo = vfo
+	for _, carrier := range carriers {
+		c := s.channelAt(carrier)
+		if c == nil {
+			if len(s.channels) >= maxChannels {
+				continue
+			}
+			c = newChannel(carrier, float64(s.sampleRate)/float64(s.decimation))
+			s.channels[carrier] = c
+		}
+		c.lastSeen = now
+	}
+
+	for f, c := range s.channels {
+		if now.Sub(c.lastSeen) > channelTimeout {
+			delete(s.channels, f)
+		}
+	}
+	s.configureStage()
+}
+
+// configureStage selects the decimation and the center frequency of the first stage, so that it contains all
+// channels. If the stage changes, the channels start over with their own decimation.
+func (s *Skimmer) configureStage() {
+	if len(s.channels) == 0 {
+		return
+	}
+	from, to := core.Frequency(math.Inf(1)), core.Frequency(math.Inf(-1))
+	for f := range s.channels {
+		from = core.Frequency(math.Min(float64(from), float64(f)))
+		to = core.Frequency(math.Max(float64(to), float64(f)))
+	}
+	from -= channelMargin
+	to += channelMargin
+
+	decimation := s.stageDecimation(to - from)
+	if s.stage == nil || s.stage.factor != decimation || !s.stageContains(from, to) {
+		s.stage = newDecimator(decimation)
+		s.stageCenter = (from + to) / 2
+		for _, c := range s.channels {
+			c.decimator = nil
+		}
+	}
+	for _, c := range s.channels {
+		if c.decimator == nil {
+			c.decimator = newDecimator(s.decimation / s.stage.factor)
+		}
+	}
+}
+
+// stageDecimation returns the highest decimation of the first stage that still leaves enough bandwidth for the given
+// span. The decimation of the first stage must be a divisor of the overall decimation.
+func (s *Skimmer) stageDecimation(span core.Frequency) int {
+	for d := s.decimation; d > 1; d-- {
+		if s.decimation%d != 0 {
+			continue
+		}
+		if stagePassband*float64(s.sampleRate)/float64(d) >= float64(span) {
+			return d
+		}
+	}
+	return 1
+}
+
+func (s *Skimmer) stageRate() float64 {
+	return float64(s.sampleRate) / float64(s.stage.factor)
+}
+
+func (s *Skimmer) stageContains(from, to core.Frequency) bool {
+	halfWidth := core.Frequency(stagePassband * s.stageRate() / 2)
+	return s.stageCenter-halfWidth <= from && to <= s.stageCenter+halfWidth
+}
+
+func (s *Skimmer) channelAt(f core.Frequency) *channel {
+	for _, c := range s.channels {
+		if math.Abs(float64(c.frequency-f)) <= float64(carrierTolerance) {
+			return c
+		}
+	}
+	return nil
+}
+
+// rateOf returns the rate of the given frequency within the IQ stream.
+func (s *Skimmer) rateOf(f core.Frequency) float64 {
+	rate := float64(f-s.vfo.Frequency-s.rxCenter+s.ifCenter)/float64(s.sampleRate) - 0.5
+	return rate - math.Floor(rate+0.5)
+}
+
+func (s *Skimmer) process(block []complex128) {
+	if len(s.channels) == 0 || s.stage == nil {
+		return
+	}
+	stageSamples := s.stage.process(block, s.rateOf(s.stageCenter))
+	stageRate := s.stageRate()
+	for _, c := range s.channels {
+		c.process(stageSamples, float64(c.frequency-s.stageCenter)/stageRate)
+
+		text := c.decoder.Text()
+		if text == c.lastText {
+			continue
+		}
+		c.lastText = text
+		select {
+		case s.texts <- core.CWText{Frequency: c.frequency, Text: text, Callsign: Callsign(c.decoder.completeWords()), WPM: c.decoder.WPM()}:
+		default:
+			log.Print("CW text hangs")
+		}
+	}
+}
+
+func (s *Skimmer) q(cmd func()) {
+	select {
+	case s.command <- cmd:
+	default:
+		log.Print("CW skimmer.q hangs")
+	}
+}
+
+func newChannel(frequency core.Frequency, rate float64) *channel {
+	return &channel{
+		frequency: frequency,
+		filter:    lowpass(channelFilterTaps, channelCutoff/rate),
+		window:    make([]complex128, channelFilterTaps),
+		decoder:   NewDecoder(rate),
+	}
+}
+
+// channel mixes one carrier down to baseband, decimates and filters it, and decodes its envelope.
+type channel struct {
+	frequency core.Frequency
+	lastSeen  time.Time
+	lastText  string
+
+	decimator  *decimator // from the first stage to the channel rate
+	filter     []float64
+	window     []complex128 // the latest samples at the channel rate, for the channel filter
+	windowHead int
+
+	decoder *Decoder
+}
+
+// process the given samples of the first stage. The carrier is at the given rate within the first stage.
+func (c *channel) process(samples []complex128, rate float64) {
+	for _, v := range c.decimator.process(samples, rate) {
+		c.window[c.windowHead] = v
+		c.windowHead = (c.windowHead + 1) % len(c.window)
+
+		var re, im float64
+		for i, h := range c.filter {
+			w := c.window[(c.windowHead+i)%len(c.window)]
+			re += real(w) * h
+			im += imag(w) * h
+		}
+		c.decoder.Put(math.Hypot(re, im))
+	}
+}
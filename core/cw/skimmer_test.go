@@ -0,0 +1,113 @@
+package cw
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ftl/panacotta/core"
+)
+
+func TestCarriers(t *testing.T) {
+	fft := core.FFT{
+		Data:  make([]float64, 1000),
+		Range: core.FrequencyRange{From: 7000000, To: 7010000},
+		Peaks: []core.PeakIndexRange{
+			{From: 100, To: 105, Max: 102},
+			{From: 200, To: 300, Max: 250},
+		},
+	}
+
+	assert.Equal(t, []core.Frequency{7001025}, Carriers(fft))
+}
+
+func TestSkimmerDecodesCarriers(t *testing.T) {
+	sampleRate := 48000
+	vfo := core.VFO{Frequency: 7010000}
+	skimmer := NewSkimmer(sampleRate, 0, 0)
+	// without IF and RX offset, the VFO frequency is at the edge of the IQ stream, see rateOf
+	carrier1 := vfo.Frequency + 500
+	carrier2 := vfo.Frequency - 1500
+	tone1 := newKeyedTone(sampleRate, 500-24000, 24, "CQ DL1ABC")
+	tone2 := newKeyedTone(sampleRate, 24000-1500, 30, "TEST K1A")
+	tone2.amplitude = 0.3
+
+	skimmer.setCarriers(vfo, []core.Frequency{carrier1, carrier2}, time.Now())
+	callsigns := make(map[core.Frequency]string)
+	block1 := make([]complex128, 4800)
+	block2 := make([]complex128, len(block1))
+	for i := 0; i < 100; i++ {
+		tone1.Read(block1)
+		tone2.Read(block2)
+		for j := range block1 {
+			block1[j] += block2[j]
+		}
+		skimmer.process(block1)
+		for len(skimmer.texts) > 0 {
+			text := <-skimmer.texts
+			callsigns[text.Frequency] = text.Callsign
+		}
+	}
+
+	assert.Equal(t, "DL1ABC", callsigns[carrier1])
+	assert.Equal(t, "K1A", callsigns[carrier2])
+}
+
+func TestSkimmerChannels(t *testing.T) {
+	skimmer := NewSkimmer(48000, 0, 0)
+	vfo := core.VFO{Frequency: 7010000}
+	now := time.Now()
+
+	skimmer.setCarriers(vfo, []core.Frequency{7010000, 7011000}, now)
+	skimmer.setCarriers(vfo, []core.Frequency{7010020}, now.Add(channelTimeout))
+	assert.Equal(t, 2, len(skimmer.channels), "carriers within the tolerance share a channel")
+
+	skimmer.setCarriers(vfo, []core.Frequency{7010000}, now.Add(channelTimeout+time.Second))
+	assert.Equal(t, 1, len(skimmer.channels), "channels without carrier are closed")
+
+	carriers := make([]core.Frequency, 2*maxChannels)
+	for i := range carriers {
+		carriers[i] = 7000000 + core.Frequency(i*100)
+	}
+	skimmer.setCarriers(vfo, carriers, now)
+	assert.Equal(t, maxChannels, len(skimmer.channels))
+}
+
+func TestSkimmerStage(t *testing.T) {
+	skimmer := NewSkimmer(1800000, 67899000, -450000)
+	vfo := core.VFO{Frequency: 7010000}
+	now := time.Now()
+
+	skimmer.setCarriers(vfo, []core.Frequency{7010000, 7011000}, now)
+	assert.Equal(t, 450, skimmer.stage.factor)
+	assert.Equal(t, core.Frequency(7010500), skimmer.stageCenter)
+
+	skimmer.setCarriers(vfo, []core.Frequency{7030000}, now)
+	assert.Equal(t, 50, skimmer.stage.factor, "the first stage contains all channels")
+	for _, c := range skimmer.channels {
+		assert.Equal(t, 9, c.decimator.factor)
+	}
+}
+
+func BenchmarkSkimmer(b *testing.B) {
+	skimmer := NewSkimmer(1800000, 67899000, -450000)
+	vfo := core.VFO{Frequency: 7030000}
+	carriers := make([]core.Frequency, maxChannels)
+	for i := range carriers {
+		carriers[i] = 7020000 + core.Frequency(i*500)
+	}
+	skimmer.setCarriers(vfo, carriers, time.Now())
+	block := make([]complex128, 65536)
+	newKeyedTone(1800000, 0.1*1800000, 24, "CQ DL1ABC").Read(block)
+
+	b.SetBytes(int64(len(block)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		skimmer.process(block)
+		for len(skimmer.texts) > 0 {
+			<-skimmer.texts
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package dsp
 
-import "math"
+import (
+	"math"
+
+	"github.com/ftl/panacotta/core"
+)
 
 func newAverager(length, blockSize int) *averager {
 	result := &averager{
@@ -26,38 +30,89 @@ func (a *averager) Put(row []float64) []float64 {
 	for i := range row {
 		a.current[i] += ((row[i] - a.buffer[a.index][i]) / float64(a.length))
 	}
-	a.buffer[a.index] = row
+	copy(a.buffer[a.index], row)
 	a.index = (a.index + 1) % a.length
 	return a.current
 }
 
-func newMaxer(length, blockSize int) *maxer {
-	result := &maxer{
-		length:  length,
-		buffer:  make([][]float64, length),
-		index:   0,
+// newSmoother returns the smoother for the given averaging mode. In the hold modes, the spectrum is not smoothed, the hold
+// trace is calculated separately.
+func newSmoother(averaging core.Averaging, blockSize int) smoother {
+	switch averaging.Mode {
+	case core.AveragingMoving:
+		if averaging.Depth > 1 {
+			return newAverager(averaging.Depth, blockSize)
+		}
+	case core.AveragingExponential:
+		return newExponentialAverager(averaging.Alpha, blockSize)
+	}
+	return passThrough{}
+}
+
+type passThrough struct{}
+
+func (passThrough) Put(row []float64) []float64 {
+	return row
+}
+
+func newExponentialAverager(alpha float64, blockSize int) *exponentialAverager {
+	return &exponentialAverager{
+		alpha:   alpha,
 		current: make([]float64, blockSize),
 	}
-	for i := range result.buffer {
-		result.buffer[i] = make([]float64, blockSize)
+}
+
+type exponentialAverager struct {
+	alpha       float64
+	current     []float64
+	initialized bool
+}
+
+func (a *exponentialAverager) Put(row []float64) []float64 {
+	if !a.initialized {
+		copy(a.current, row)
+		a.initialized = true
+		return a.current
 	}
-	return result
+	for i := range row {
+		a.current[i] += a.alpha * (row[i] - a.current[i])
+	}
+	return a.current
 }
 
-type maxer struct {
-	length  int
-	buffer  [][]float64
-	index   int
-	current []float64
+// newHold returns the hold for the given averaging mode, or nil if the mode is no hold mode.
+func newHold(averaging core.Averaging, blockSize int) *hold {
+	switch averaging.Mode {
+	case core.AveragingMaxHold:
+		return &hold{current: make([]float64, blockSize), keep: math.Max}
+	case core.AveragingMinHold:
+		return &hold{current: make([]float64, blockSize), keep: math.Min}
+	default:
+		return nil
+	}
+}
+
+// hold keeps the maximum or minimum value of each bin until it is reset.
+type hold struct {
+	current     []float64
+	keep        func(float64, float64) float64
+	initialized bool
 }
 
-func (m *maxer) Put(row []float64) []float64 {
+func (h *hold) Put(row []float64) []float64 {
+	if !h.initialized {
+		copy(h.current, row)
+		h.initialized = true
+		return h.current
+	}
 	for i := range row {
-		m.current[i] = math.Max(m.buffer[m.index][i], row[i])
+		h.current[i] = h.keep(h.current[i], row[i])
 	}
-	m.buffer[m.index] = row
-	m.index = (m.index + 1) % m.length
-	return m.current
+	return h.current
+}
+
+func (h *hold) Reset() {
+	h.initialized = false
 }
 
 func newSlidingWindow(length int) *slidingWindow {
@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/ftl/panacotta/core"
 )
 
 func TestSlidingMax(t *testing.T) {
@@ -29,3 +31,38 @@ func TestSlidingMax(t *testing.T) {
 		})
 	}
 }
+
+func TestExponentialAverager(t *testing.T) {
+	a := newExponentialAverager(0.5, 2)
+
+	assert.Equal(t, []float64{4, -4}, a.Put([]float64{4, -4}))
+	assert.Equal(t, []float64{2, -2}, a.Put([]float64{0, 0}))
+	assert.Equal(t, []float64{1, -1}, a.Put([]float64{0, 0}))
+}
+
+func TestHold(t *testing.T) {
+	maxHold := newHold(core.Averaging{Mode: core.AveragingMaxHold}, 3)
+	minHold := newHold(core.Averaging{Mode: core.AveragingMinHold}, 3)
+
+	for _, row := range [][]float64{{1, 5, 3}, {4, 2, 3}, {0, 6, 1}} {
+		maxHold.Put(row)
+		minHold.Put(row)
+	}
+
+	assert.Equal(t, []float64{4, 6, 3}, maxHold.current)
+	assert.Equal(t, []float64{0, 2, 1}, minHold.current)
+
+	maxHold.Reset()
+
+	assert.Equal(t, []float64{-1, -2, -3}, maxHold.Put([]float64{-1, -2, -3}))
+}
+
+func TestNewSmoother(t *testing.T) {
+	assert.IsType(t, passThrough{}, newSmoother(core.Averaging{Mode: core.AveragingNone}, 4))
+	assert.IsType(t, &averager{}, newSmoother(core.Averaging{Mode: core.AveragingMoving, Depth: 5}, 4))
+	assert.IsType(t, passThrough{}, newSmoother(core.Averaging{Mode: core.AveragingMoving, Depth: 1}, 4))
+	assert.IsType(t, &exponentialAverager{}, newSmoother(core.Averaging{Mode: core.AveragingExponential, Alpha: 0.3}, 4))
+	assert.IsType(t, passThrough{}, newSmoother(core.Averaging{Mode: core.AveragingMaxHold}, 4))
+
+	assert.Nil(t, newHold(core.Averaging{Mode: core.AveragingExponential}, 4))
+}
@@ -0,0 +1,84 @@
+package dsp
+
+import (
+	"math"
+	"sync"
+
+	"github.com/ftl/panacotta/core/iq"
+)
+
+// ConvertUint8Samples converts a block of interleaved unsigned 8-bit samples, as delivered by RTL-SDR dongles, into complex samples.
+func ConvertUint8Samples(block []byte) []complex128 {
+	return ConvertUint8SamplesInto(make([]complex128, len(block)/2), block)
+}
+
+// ConvertUint8SamplesInto converts a block of interleaved unsigned 8-bit samples into the given block of complex samples,
+// which must hold at least half as many samples as the input block. It returns the converted part of the given block.
+// The conversion is the same as for IQ files in the cu8 format, so that replayed raw dumps look like the live input.
+func ConvertUint8SamplesInto(samples []complex128, block []byte) []complex128 {
+	return iq.DecodeUint8(samples, block)
+}
+
+// ClippingUint8 returns the ratio of samples in the given block of interleaved unsigned 8-bit samples where I or Q is at
+// the limit of the ADC range.
+func ClippingUint8(block []byte) float64 {
+	count := len(block) / 2
+	if count == 0 {
+		return 0
+	}
+	clipped := 0
+	for i := 0; i < count; i++ {
+		if isClippedUint8(block[2*i]) || isClippedUint8(block[2*i+1]) {
+			clipped++
+		}
+	}
+	return float64(clipped) / float64(count)
+}
+
+func isClippedUint8(b byte) bool {
+	return b == 0 || b == math.MaxUint8
+}
+
+// NewClippingRecord returns a new empty ClippingRecord.
+func NewClippingRecord() *ClippingRecord {
+	return &ClippingRecord{
+		ratios: make(map[*complex128]float64),
+	}
+}
+
+// ClippingRecord keeps the clipping ratio of the blocks that a samples input hands out, until the blocks are released.
+// A record is safe for concurrent use.
+type ClippingRecord struct {
+	lock   sync.Mutex
+	ratios map[*complex128]float64
+}
+
+// Put the clipping ratio of the given block into the record.
+func (r *ClippingRecord) Put(block []complex128, ratio float64) {
+	if len(block) == 0 {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.ratios[&block[0]] = ratio
+}
+
+// Get the clipping ratio of the given block. Unknown blocks are not clipped.
+func (r *ClippingRecord) Get(block []complex128) float64 {
+	if len(block) == 0 {
+		return 0
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.ratios[&block[0]]
+}
+
+// Remove the given block from the record.
+func (r *ClippingRecord) Remove(block []complex128) {
+	if len(block) == 0 {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.ratios, &block[0])
+}
@@ -0,0 +1,74 @@
+package dsp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertUint8Samples(t *testing.T) {
+	block := []byte{0, 255, 255, 0, 255, 255}
+
+	actual := ConvertUint8Samples(block)
+
+	assert.Equal(t, []complex128{complex(-1, 1), complex(1, -1), complex(1, 1)}, actual)
+}
+
+func TestConvertUint8SamplesInto(t *testing.T) {
+	block := make([]byte, 512)
+	for i := range block {
+		block[i] = byte(i)
+	}
+	samples := make([]complex128, 300)
+
+	actual := ConvertUint8SamplesInto(samples, block)
+
+	assert.Equal(t, 256, len(actual))
+	assert.Equal(t, &samples[0], &actual[0])
+	for i, s := range actual {
+		assert.Equal(t, complex(normalized(block[2*i]), normalized(block[2*i+1])), s)
+	}
+}
+
+func TestClippingUint8(t *testing.T) {
+	assert.Equal(t, 0.0, ClippingUint8([]byte{}))
+	assert.Equal(t, 0.0, ClippingUint8([]byte{1, 254, 128, 127}))
+	assert.Equal(t, 0.5, ClippingUint8([]byte{0, 128, 1, 254, 127, 255, 254, 1}))
+}
+
+func TestClippingRecord(t *testing.T) {
+	record := NewClippingRecord()
+	block1 := make([]complex128, 4)
+	block2 := make([]complex128, 4)
+
+	record.Put(block1, 0.25)
+	record.Put(block2, 0.5)
+	assert.Equal(t, 0.25, record.Get(block1))
+	assert.Equal(t, 0.5, record.Get(block2))
+
+	record.Remove(block1)
+	assert.Equal(t, 0.0, record.Get(block1))
+	assert.Equal(t, 0.0, record.Get(nil))
+}
+
+func normalized(b byte) float64 {
+	return (float64(b) - 127.5) / 127.5
+}
+
+func BenchmarkConvertUint8Samples(b *testing.B) {
+	block := make([]byte, 2*65536)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ConvertUint8Samples(block)
+	}
+}
+
+func BenchmarkConvertUint8SamplesInto(b *testing.B) {
+	block := make([]byte, 2*65536)
+	pool := NewBlockPool(1)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		samples := ConvertUint8SamplesInto(pool.Get(65536), block)
+		pool.Put(samples)
+	}
+}
@@ -0,0 +1,150 @@
+package dsp
+
+import (
+	"math"
+
+	"github.com/ftl/panacotta/core"
+)
+
+const (
+	dcAveragingFactor        = 0.1
+	imbalanceAveragingFactor = 0.05
+)
+
+// NewCorrection returns the chain of correction stages that is configured by the given settings.
+func NewCorrection(settings core.IQCorrection) *Correction {
+	result := &Correction{}
+	if settings.SwapIQ {
+		result.stages = append(result.stages, stageFunc(swapIQ))
+	}
+	if settings.InvertSpectrum {
+		result.stages = append(result.stages, stageFunc(invertSpectrum))
+	}
+	if settings.RemoveDC {
+		result.stages = append(result.stages, new(dcRemoval))
+	}
+	if settings.CorrectImbalance {
+		result.stages = append(result.stages, newImbalanceCorrection())
+	}
+	return result
+}
+
+// Correction applies a chain of correction stages to the incoming samples, before they are processed by the DSP.
+type Correction struct {
+	stages []stage
+}
+
+// stage corrects a block of samples in place.
+type stage interface {
+	Process(samples []complex128)
+}
+
+type stageFunc func([]complex128)
+
+func (f stageFunc) Process(samples []complex128) {
+	f(samples)
+}
+
+// Process the given samples in place.
+func (c *Correction) Process(samples []complex128) {
+	for _, s := range c.stages {
+		s.Process(samples)
+	}
+}
+
+// swapIQ exchanges the I and Q component of each sample.
+func swapIQ(samples []complex128) {
+	for i, s := range samples {
+		samples[i] = complex(imag(s), real(s))
+	}
+}
+
+// invertSpectrum mirrors the spectrum at the center frequency.
+func invertSpectrum(samples []complex128) {
+	for i, s := range samples {
+		samples[i] = complex(real(s), -imag(s))
+	}
+}
+
+// dcRemoval subtracts the DC offset, which is tracked as the exponential average of the mean of each block.
+type dcRemoval struct {
+	offset      complex128
+	initialized bool
+}
+
+func (r *dcRemoval) Process(samples []complex128) {
+	if len(samples) == 0 {
+		return
+	}
+
+	var sum complex128
+	for _, s := range samples {
+		sum += s
+	}
+	mean := sum / complex(float64(len(samples)), 0)
+
+	if r.initialized {
+		r.offset += complex(dcAveragingFactor, 0) * (mean - r.offset)
+	} else {
+		r.offset = mean
+		r.initialized = true
+	}
+
+	for i := range samples {
+		samples[i] -= r.offset
+	}
+}
+
+func newImbalanceCorrection() *imbalanceCorrection {
+	return &imbalanceCorrection{
+		phase: 0,
+		gain:  1,
+	}
+}
+
+// imbalanceCorrection estimates the gain and phase imbalance between I and Q blindly from the signal statistics and
+// makes Q orthogonal to I with the same power. The estimates are tracked as exponential averages.
+type imbalanceCorrection struct {
+	phase       float64 // correlation of Q with I
+	gain        float64 // ratio of the power of I and the power of the orthogonalized Q
+	initialized bool
+}
+
+func (c *imbalanceCorrection) Process(samples []complex128) {
+	if len(samples) == 0 {
+		return
+	}
+
+	var ii, iq float64
+	for _, s := range samples {
+		ii += real(s) * real(s)
+		iq += real(s) * imag(s)
+	}
+	if ii == 0 {
+		return
+	}
+	phase := iq / ii
+
+	var qq float64
+	for _, s := range samples {
+		q := imag(s) - phase*real(s)
+		qq += q * q
+	}
+	if qq == 0 {
+		return
+	}
+	gain := math.Sqrt(ii / qq)
+
+	if c.initialized {
+		c.phase += imbalanceAveragingFactor * (phase - c.phase)
+		c.gain += imbalanceAveragingFactor * (gain - c.gain)
+	} else {
+		c.phase = phase
+		c.gain = gain
+		c.initialized = true
+	}
+
+	for i, s := range samples {
+		samples[i] = complex(real(s), c.gain*(imag(s)-c.phase*real(s)))
+	}
+}
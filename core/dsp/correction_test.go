@@ -0,0 +1,70 @@
+package dsp
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+
+	dsp "github.com/mjibson/go-dsp/fft"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ftl/panacotta/core"
+)
+
+func TestCorrectionSwapAndInvert(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		settings core.IQCorrection
+		expected complex128
+	}{
+		{"none", core.IQCorrection{}, complex(1, 2)},
+		{"swap", core.IQCorrection{SwapIQ: true}, complex(2, 1)},
+		{"invert", core.IQCorrection{InvertSpectrum: true}, complex(1, -2)},
+		{"both", core.IQCorrection{SwapIQ: true, InvertSpectrum: true}, complex(2, -1)},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			samples := []complex128{complex(1, 2)}
+
+			NewCorrection(tC.settings).Process(samples)
+
+			assert.Equal(t, tC.expected, samples[0])
+		})
+	}
+}
+
+func TestCorrectionRemoveDC(t *testing.T) {
+	blockSize := 1024
+	correction := NewCorrection(core.IQCorrection{RemoveDC: true})
+
+	for i := 0; i < 3; i++ {
+		samples := tone(blockSize, 0.25)
+		for j := range samples {
+			samples[j] += complex(0.2, -0.1)
+		}
+
+		correction.Process(samples)
+
+		fft := dsp.FFT(samples)
+		assert.InDelta(t, 0, cmplx.Abs(fft[0])/float64(blockSize), 1e-9)
+		assert.InDelta(t, 1, cmplx.Abs(fft[peakIndex(0.25, blockSize)])/float64(blockSize), 1e-9)
+	}
+}
+
+func TestCorrectionImbalance(t *testing.T) {
+	blockSize := 1024
+	f := 0.125
+	samples := make([]complex128, blockSize)
+	for i := range samples {
+		ω := 2 * math.Pi * f * float64(i)
+		samples[i] = complex(math.Cos(ω), 0.8*math.Sin(ω+0.1))
+	}
+	image := peakIndex(-f, blockSize)
+
+	before := cmplx.Abs(dsp.FFT(samples)[image])
+	NewCorrection(core.IQCorrection{CorrectImbalance: true}).Process(samples)
+	after := cmplx.Abs(dsp.FFT(samples)[image])
+
+	assert.True(t, before > 0.1*float64(blockSize), "image before %f", before)
+	assert.InDelta(t, 0, after/float64(blockSize), 1e-6)
+}
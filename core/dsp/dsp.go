@@ -14,21 +14,25 @@ import (
 	"github.com/ftl/panacotta/core"
 )
 
-const smoothingDepth = 5 // 10 // TODO make configurable
 type smoother interface {
 	Put([]float64) []float64
 }
 
 func New(sampleRate int, ifFrequency, rxOffset core.Frequency) *DSP {
 	result := &DSP{
-		workInput: make(chan work, 1),
+		workInput: make(chan work, 4),
 		fft:       make(chan core.FFT, 1),
+		command:   make(chan func(), 8),
+		blocks:    NewBlockPool(16),
+		pending:   make([]*frameJob, 2),
 
 		sampleRate:  sampleRate,
 		ifCenter:    ifFrequency,
 		rxCenter:    ifFrequency + rxOffset,
 		filterCoeff: firLowpass(27, 1.0/4.8),
-		smoother:    newAverager(smoothingDepth, 0),
+		averaging:   core.Averaging{Mode: core.AveragingMoving, Depth: 5, Alpha: 0.3},
+
+		peakDetection: core.PeakDetection{WindowSize: 9, ThresholdOffset: 3},
 	}
 
 	return result
@@ -43,6 +47,8 @@ func NewFullRange(sampleRate int, ifFrequency, rxOffset core.Frequency) *DSP {
 type DSP struct {
 	workInput chan work
 	fft       chan core.FFT
+	command   chan func()
+	blocks    *BlockPool
 
 	sampleRate int
 	ifCenter   core.Frequency
@@ -50,20 +56,46 @@ type DSP struct {
 
 	vfo                core.VFO
 	fftRange           core.FrequencyRange
-	fftWindow          []complex128
+	window             core.FFTWindow
+	fftWindow          []float64
+	fftPlan            *fftPlan
 	inputBlockSize     int
 	outputBlockSize    int
 	fftRangeOffsetRate float64
 	decimation         int
 	filterCoeff        []complex128
-	filterWindow       []complex128
+	filterWindow       []complex64
 	fullRangeMode      bool
-
-	smoother smoother
+	spectrum           []float64
+	power              []float64 // accumulated power spectra
+	frameCount         int
+	clipping           float64
+
+	overlap         float64 // 0 means no streaming, only one frame per block
+	fftPerSecond    int
+	stream          []complex128
+	streamFill      int
+	samplesSinceFFT int
+
+	averaging core.Averaging
+	smoother  smoother
+	hold      *hold
+
+	peakDetection core.PeakDetection
+
+	jobs          chan *frameJob
+	results       chan *frameJob
+	pending       []*frameJob // processed frames waiting for their predecessors, indexed by seq
+	freeJobs      []*frameJob
+	inFlight      int
+	dispatchedSeq uint64
+	collectedSeq  uint64
+	generation    int // frames of an older generation were processed with an outdated configuration
 }
 
 type work struct {
 	samples  []complex128
+	clipping float64
 	fftRange core.FrequencyRange
 	vfo      core.VFO
 }
@@ -74,17 +106,28 @@ func (d *DSP) Run(stop chan struct{}) {
 		select {
 		case work := <-d.workInput:
 			d.doWork(work)
+		case job := <-d.results:
+			d.inFlight--
+			d.collect(job)
+		case command := <-d.command:
+			command()
 		case <-stop:
+			d.stopWorkers()
 			close(d.fft)
 			return
 		}
 	}
 }
 
-func (d *DSP) ProcessSamples(samples []complex128, fftRange core.FrequencyRange, vfo core.VFO) {
+// ProcessSamples calculates the FFT of the given samples asynchronously. The clipping ratio of the raw samples is passed
+// on with the resulting FFT. The samples are copied, the caller may reuse the given block afterwards.
+func (d *DSP) ProcessSamples(samples []complex128, clipping float64, fftRange core.FrequencyRange, vfo core.VFO) {
+	block := d.blocks.Get(len(samples))
+	copy(block, samples)
 	select {
-	case d.workInput <- work{samples, fftRange, vfo}:
+	case d.workInput <- work{block, clipping, fftRange, vfo}:
 	default:
+		d.blocks.Put(block)
 		log.Print("process samples hangs")
 	}
 }
@@ -93,6 +136,95 @@ func (d *DSP) FFT() chan core.FFT {
 	return d.fft
 }
 
+// SetAveraging selects how the spectrum is averaged over time. The averaged spectrum and the hold trace start over.
+func (d *DSP) SetAveraging(averaging core.Averaging) {
+	d.q(func() {
+		d.setAveraging(averaging)
+	})
+}
+
+func (d *DSP) setAveraging(averaging core.Averaging) {
+	d.drainFrames()
+
+	d.averaging = averaging
+	d.smoother = newSmoother(d.averaging, d.outputBlockSize)
+	d.hold = newHold(d.averaging, d.outputBlockSize)
+	log.Printf("averaging: %s", d.averaging)
+}
+
+// SetPeakDetection sets the parameters that control the sensitivity of the peak detection.
+func (d *DSP) SetPeakDetection(peakDetection core.PeakDetection) {
+	d.q(func() {
+		d.peakDetection = peakDetection.Normalized()
+	})
+}
+
+// ResetHold resets the hold trace in the max-hold or min-hold averaging mode.
+func (d *DSP) ResetHold() {
+	d.q(func() {
+		if d.hold != nil {
+			d.hold.Reset()
+		}
+	})
+}
+
+// ResetAveraging discards the smoothed spectrum and the accumulated power spectra, e.g. after a transmission. The hold
+// trace is kept.
+func (d *DSP) ResetAveraging() {
+	d.q(d.resetAveraging)
+}
+
+func (d *DSP) resetAveraging() {
+	if d.smoother != nil {
+		d.smoother = newSmoother(d.averaging, d.outputBlockSize)
+	}
+	d.streamFill = 0
+	d.samplesSinceFFT = 0
+	d.generation++
+	d.resetPower()
+}
+
+// SetOverlap enables the streaming mode if the given overlap is greater than 0. In the streaming mode, all incoming samples
+// are processed as continuous stream of overlapping frames and the given number of FFTs per second is returned. The
+// supported overlaps are 50% and 75%, other values are rounded down to the next supported overlap.
+func (d *DSP) SetOverlap(overlap float64, fftPerSecond int) {
+	d.q(func() {
+		d.overlap = supportedOverlap(overlap)
+		d.fftPerSecond = fftPerSecond
+		d.streamFill = 0
+		d.samplesSinceFFT = 0
+		d.generation++
+		d.resetPower()
+	})
+}
+
+func supportedOverlap(overlap float64) float64 {
+	switch {
+	case overlap >= 0.75:
+		return 0.75
+	case overlap >= 0.5:
+		return 0.5
+	default:
+		return 0
+	}
+}
+
+// SetWindow selects the window that is applied to the samples before the FFT.
+func (d *DSP) SetWindow(window core.FFTWindow) {
+	d.q(func() {
+		d.window = window
+		d.fftWindow = nil
+	})
+}
+
+func (d *DSP) q(command func()) {
+	select {
+	case d.command <- command:
+	default:
+		log.Print("DSP.q hangs")
+	}
+}
+
 func findBlocksize(width, max int) int {
 	result := dsputils.NextPowerOf2(width)
 	if result > max {
@@ -111,13 +243,29 @@ func toRate(frequency float64, sampleRate int) float64 {
 
 func (d *DSP) doWork(work work) {
 	if work.fftRange.Width() == 0 {
+		d.blocks.Put(work.samples)
 		return
 	}
 
+	d.reconfigure(work)
+	d.clipping = work.clipping
+
+	if d.overlap == 0 {
+		d.dispatchFrame(work.samples, true)
+		return
+	}
+	d.processStream(work.samples)
+	d.blocks.Put(work.samples)
+}
+
+func (d *DSP) reconfigure(work work) {
 	oldOutputBlockSize := d.outputBlockSize
 	var needReconfiguration bool
 	if len(work.samples) != d.inputBlockSize || needReconfiguration {
 		d.inputBlockSize = len(work.samples)
+		d.fftPlan = newFFTPlan(d.inputBlockSize)
+		d.stream = make([]complex128, d.inputBlockSize)
+		d.streamFill = 0
 		needReconfiguration = true
 	}
 	if work.vfo.Frequency != d.vfo.Frequency || needReconfiguration {
@@ -132,31 +280,99 @@ func (d *DSP) doWork(work work) {
 		d.filterWindow = fftLowpass(d.inputBlockSize, d.decimation)
 		needReconfiguration = true
 	}
-	if oldOutputBlockSize != d.outputBlockSize {
-		d.smoother = newAverager(smoothingDepth, d.outputBlockSize)
-
-		fftWindow := window.Hamming(d.outputBlockSize)
-		d.fftWindow = make([]complex128, len(fftWindow))
-		for i := range fftWindow {
-			d.fftWindow[i] = complex(fftWindow[i], 0)
-		}
+	if oldOutputBlockSize != d.outputBlockSize || d.smoother == nil {
+		d.spectrum = make([]float64, d.outputBlockSize)
+		d.power = make([]float64, d.outputBlockSize)
+		d.frameCount = 0
+		d.smoother = newSmoother(d.averaging, d.outputBlockSize)
+		d.hold = newHold(d.averaging, d.outputBlockSize)
+		log.Printf("averaging: %s", d.averaging)
+	} else if needReconfiguration && d.hold != nil {
+		d.hold.Reset()
+	}
+	if len(d.fftWindow) != d.inputBlockSize {
+		d.fftWindow = fftWindow(d.window, d.inputBlockSize)
+		log.Printf("FFT window: %s", d.window)
 	}
 	if needReconfiguration {
+		d.generation++
+		d.resetPower()
 		log.Printf("fftRange %f %f %f (%f) | vfo %f | if %f | rx %f", d.fftRange.From, d.fftRange.Center(), d.fftRange.To, d.fftRange.Width(), d.vfo.Frequency, d.ifCenter, d.rxCenter)
 		log.Printf("reconfiguration: %d %d %f %f", d.decimation, d.outputBlockSize, d.fftRange.Width(), d.fftRangeOffsetRate)
 	}
+}
+
+// processStream appends the given samples to the continuous stream of samples and processes all complete frames.
+// Consecutive frames overlap by the configured ratio, the power spectra of all frames within one output interval are
+// averaged (Welch's method).
+func (d *DSP) processStream(samples []complex128) {
+	hop := int(float64(len(d.stream)) * (1 - d.overlap))
+	if hop < 1 {
+		hop = 1
+	}
+	for len(samples) > 0 {
+		n := copy(d.stream[d.streamFill:], samples)
+		samples = samples[n:]
+		d.streamFill += n
+		d.samplesSinceFFT += n
+		if d.streamFill < len(d.stream) {
+			continue
+		}
+
+		emit := d.samplesSinceFFT >= d.samplesPerFFT()
+		if emit {
+			d.samplesSinceFFT = 0
+		}
+		frame := d.blocks.Get(len(d.stream))
+		copy(frame, d.stream)
+		d.dispatchFrame(frame, emit)
+
+		copy(d.stream, d.stream[hop:])
+		d.streamFill -= hop
+	}
+}
 
-	spectrum, mean := fftSlice(work.samples, d.outputBlockSize, d.fftRangeOffsetRate, d.filterWindow)
-	if smoothingDepth > 1 {
-		spectrum = d.smoother.Put(spectrum)
+func (d *DSP) samplesPerFFT() int {
+	if d.fftPerSecond <= 0 {
+		return 0
+	}
+	return d.sampleRate / d.fftPerSecond
+}
+
+func (d *DSP) resetPower() {
+	for i := range d.power {
+		d.power[i] = 0
+	}
+	d.frameCount = 0
+}
+
+// emitFFT sends the average of the accumulated power spectra.
+func (d *DSP) emitFFT() {
+	if d.frameCount == 0 {
+		return
+	}
+	spectrum := d.spectrum
+	mean := powerToDBm(spectrum, d.power, d.frameCount, d.inputBlockSize)
+	d.resetPower()
+
+	spectrum = copyOf(d.smoother.Put(spectrum))
+	var hold []float64
+	if d.hold != nil {
+		hold = copyOf(d.hold.Put(spectrum))
 	}
-	_, sigmaEnvelope := centeredSlidingWindowAverageAndSigmaEnvelope(spectrum, 9) // TODO windowSize is a config parameter for peak detection, controls sensitivity
-	peaks, threshold := peaks(spectrum, sigmaEnvelope, mean)
 
 	center := d.fftRange.Center()
 	sideband := core.Frequency(d.sampleRate / (2 * d.decimation))
+
+	_, sigmaEnvelope := centeredSlidingWindowAverageAndSigmaEnvelope(spectrum, d.peakDetection.WindowSize)
+	noiseFloor, noiseσ := noiseFloor(spectrum)
+	minPeakWidth := int(math.Ceil(float64(d.peakDetection.MinWidth) * float64(len(spectrum)) / float64(2*sideband)))
+	peaks, threshold := peaks(spectrum, sigmaEnvelope, noiseFloor, noiseσ, d.peakDetection.ThresholdOffset, minPeakWidth)
 	if d.fullRangeMode {
 		spectrum = padZero(spectrum, d.inputBlockSize)
+		if hold != nil {
+			hold = padZero(hold, d.inputBlockSize)
+		}
 		sideband = core.Frequency(d.sampleRate / 2)
 	}
 
@@ -165,15 +381,34 @@ func (d *DSP) doWork(work work) {
 		Data:          spectrum,
 		Range:         core.FrequencyRange{From: center - sideband, To: center + sideband},
 		Mean:          mean,
+		NoiseFloor:    noiseFloor,
 		PeakThreshold: threshold,
 		SigmaEnvelope: sigmaEnvelope,
 		Peaks:         peaks,
+		Clipping:      d.clipping,
+		Window:        d.window,
+		Averaging:     d.averaging,
+		Hold:          hold,
 	}:
 	default:
 		log.Print("return FFT hangs")
 	}
 }
 
+// applyWindow multiplies the given samples in place with the given window.
+func applyWindow(samples []complex128, window []float64) {
+	for i, w := range window {
+		samples[i] *= complex(w, 0)
+	}
+}
+
+// copyOf returns a copy of the given values, so they can be handed over to another goroutine.
+func copyOf(values []float64) []float64 {
+	result := make([]float64, len(values))
+	copy(result, values)
+	return result
+}
+
 func padZero(samples []float64, size int) []float64 {
 	pad := make([]float64, (size-len(samples))/2)
 	result := make([]float64, 0, size)
@@ -186,200 +421,86 @@ func padZero(samples []float64, size int) []float64 {
 	return result
 }
 
-func shift(samples []complex128, shiftRate float64) []complex128 {
-	outputSamples := make([]complex128, len(samples))
-
+// shift writes the given samples, shifted by the given rate, into the given result, which must be as long as the samples.
+// The result may be the samples themselves.
+func shift(result []complex128, samples []complex128, shiftRate float64) []complex128 {
+	result = result[:len(samples)]
 	ω := 2 * math.Pi * shiftRate
 	for i, s := range samples {
 		t := float64(i)
-		outputSamples[i] = s * cmplx.Exp(complex(0, ω*t)) // shift fftRange to center of fullRange
-	}
-
-	return outputSamples
-}
-
-func filter(samples []complex128, filterCoeff []complex128) []complex128 {
-	blockSize := len(samples)
-	filterOrder := len(filterCoeff)
-
-	outputSamples := make([]complex128, blockSize)
-	for i := range samples {
-		j := (i - filterOrder + 1)
-		for k := filterOrder - 1; k >= 0; k-- {
-			if j >= 0 && j < blockSize {
-				outputSamples[i] += samples[j] * filterCoeff[k]
-			}
-			j++
-		}
+		result[i] = s * cmplx.Exp(complex(0, ω*t)) // shift fftRange to center of fullRange
 	}
 
-	return outputSamples
+	return result
 }
 
-func shiftAndFilter(samples []complex128, shiftRate float64, filterCoeff []complex128) []complex128 {
-	ω := 2 * math.Pi * shiftRate
-
+// filter writes the given samples, filtered with the given FIR coefficients, into the given result, which must be as
+// long as the samples and must not overlap with them.
+func filter(result []complex128, samples []complex128, filterCoeff []complex128) []complex128 {
 	blockSize := len(samples)
 	filterOrder := len(filterCoeff)
 
-	shiftedSamples := make([]complex128, blockSize)
-	lastShifted := -1
-	outputSamples := make([]complex128, blockSize)
-
+	result = result[:blockSize]
 	for i := range samples {
-		t := float64(i)
-
+		result[i] = 0
 		j := (i - filterOrder + 1)
 		for k := filterOrder - 1; k >= 0; k-- {
 			if j >= 0 && j < blockSize {
-				var s complex128
-				if j <= lastShifted {
-					s = shiftedSamples[j]
-				} else {
-					s = samples[j] * cmplx.Exp(complex(0, ω*t))
-					shiftedSamples[j] = s
-					lastShifted = j
-				}
-				outputSamples[i] += s * filterCoeff[k]
+				result[i] += samples[j] * filterCoeff[k]
 			}
 			j++
 		}
 	}
 
-	return outputSamples
-}
-
-func downsample(samples []complex128, decimation int) []complex128 {
-	result := make([]complex128, len(samples)/decimation)
-	for i := range result {
-		result[i] = samples[i*decimation]
-	}
 	return result
 }
 
-func decimate(samples []complex128, decimation int, filterCoeff []complex128) []complex128 {
-	blockSize := len(samples)
-	filterOrder := len(filterCoeff)
-
-	outputSamples := make([]complex128, blockSize/decimation)
-	outputIndex := 0
-	for i := 0; i < blockSize; i += decimation {
-		j := (i - filterOrder + 1)
-		for k := filterOrder - 1; k >= 0; k-- {
-			if j >= 0 && j < blockSize {
-				outputSamples[outputIndex] += samples[j] * filterCoeff[k]
-			}
-			j++
-		}
-		outputIndex++
-	}
-
-	return outputSamples
+// shiftAndFilter shifts the given samples in place and writes them, filtered with the given FIR coefficients, into the
+// given result.
+func shiftAndFilter(result []complex128, samples []complex128, shiftRate float64, filterCoeff []complex128) []complex128 {
+	return filter(result, shift(samples, samples, shiftRate), filterCoeff)
 }
 
-func shiftAndDecimate(samples []complex128, shiftRate float64, decimation int, filterCoeff []complex128) []complex128 {
-	ω := 2 * math.Pi * shiftRate
-
+// decimate writes every n-th of the given samples, filtered with the given FIR coefficients, into the given result,
+// which must hold len(samples)/decimation samples and must not overlap with the samples.
+func decimate(result []complex128, samples []complex128, decimation int, filterCoeff []complex128) []complex128 {
 	blockSize := len(samples)
 	filterOrder := len(filterCoeff)
 
-	shiftedSamples := make([]complex128, blockSize)
-	lastShifted := -1
-	outputSamples := make([]complex128, blockSize/decimation)
+	result = result[:blockSize/decimation]
 	outputIndex := 0
-
 	for i := 0; i < blockSize; i += decimation {
-		t := float64(i)
-
+		result[outputIndex] = 0
 		j := (i - filterOrder + 1)
 		for k := filterOrder - 1; k >= 0; k-- {
 			if j >= 0 && j < blockSize {
-				var s complex128
-				if j <= lastShifted {
-					s = shiftedSamples[j]
-				} else {
-					s = samples[j] * cmplx.Exp(complex(0, ω*t))
-					shiftedSamples[j] = s
-					lastShifted = j
-				}
-				outputSamples[outputIndex] += s * filterCoeff[k]
+				result[outputIndex] += samples[j] * filterCoeff[k]
 			}
 			j++
 		}
 		outputIndex++
 	}
 
-	return outputSamples
-}
-
-func fftDecimate(samples []complex128, decimation int, filterWindow []complex128) []complex128 {
-	frequencyDomain := dsp.FFT(samples)
-	for i := range frequencyDomain {
-		frequencyDomain[i] *= filterWindow[i]
-	}
-	timeDomain := dsp.IFFT(frequencyDomain)
-
-	result := make([]complex128, len(samples)/decimation)
-	for i := 0; i < len(timeDomain); i += decimation {
-		result[i/decimation] = timeDomain[i]
-	}
-
 	return result
 }
 
-func fftShiftAndDecimate(samples []complex128, shiftRate float64, decimation int, filterWindow []complex128) []complex128 {
-	frequencyDomain := dsp.FFT(samples)
-	blockSize := len(frequencyDomain)
-	shiftOffset := int(shiftRate * float64(blockSize))
-	shifted := make([]complex128, blockSize)
-	for i := range frequencyDomain {
-		shiftedIndex := (blockSize + shiftOffset + i) % blockSize
-		shifted[shiftedIndex] = frequencyDomain[i] * filterWindow[shiftedIndex]
-	}
-	timeDomain := dsp.IFFT(shifted)
-
-	result := make([]complex128, len(samples)/decimation)
-	for i := 0; i < len(timeDomain); i += decimation {
-		result[i/decimation] = timeDomain[i]
-	}
-
-	return result
-}
-
-func fft(samples []complex128) ([]float64, float64) {
-	cfft := dsp.FFT(samples)
-
-	result := make([]float64, len(cfft))
-	mean := 0.0
-	blockSize := len(result)
-	blockCenter := blockSize / 2
-	for i, v := range cfft {
-		var resultIndex int
-		if i < blockCenter {
-			resultIndex = i + blockCenter
-		} else {
-			resultIndex = i - blockCenter
-		}
-
-		result[resultIndex] = fftValue2dBm(v, blockSize)
-		mean += result[resultIndex]
-	}
-	mean /= float64(blockSize)
-	return result, mean
+// shiftAndDecimate shifts the given samples in place and writes every n-th of them, filtered with the given FIR
+// coefficients, into the given result.
+func shiftAndDecimate(result []complex128, samples []complex128, shiftRate float64, decimation int, filterCoeff []complex128) []complex128 {
+	return decimate(result, shift(samples, samples, shiftRate), decimation, filterCoeff)
 }
 
 func fftValue2dBm(fftValue complex128, blockSize int) float64 {
 	return 10.0 * math.Log10(20.0*(math.Pow(real(fftValue), 2)+math.Pow(imag(fftValue), 2))/math.Pow(float64(blockSize), 2))
 }
 
-func fftSlice(samples []complex128, sliceLength int, sliceCenterOffsetRate float64, filterWindow []complex128) ([]float64, float64) {
-	frequencyDomain := dsp.FFT(samples)
+// fftSlice adds the power of the slice of the given spectrum around the given center offset to the given result.
+func fftSlice(result []float64, frequencyDomain []complex128, sliceCenterOffsetRate float64, filterWindow []complex64) {
 	blockSize := len(frequencyDomain)
 	blockCenter := blockSize / 2
+	sliceLength := len(result)
 	shiftOffset := int(sliceCenterOffsetRate * float64(blockSize))
 	resultOffset := (blockSize - sliceLength) / 2
-	result := make([]float64, sliceLength)
-	mean := 0.0
 	for i := range frequencyDomain {
 		shiftedIndex := (blockSize + blockCenter - shiftOffset + i) % blockSize
 		var resultIndex int
@@ -391,28 +512,32 @@ func fftSlice(samples []complex128, sliceLength int, sliceCenterOffsetRate float
 		resultIndex -= resultOffset
 
 		if 0 <= resultIndex && resultIndex < len(result) {
-			result[resultIndex] = fftValue2dBm(frequencyDomain[i]*filterWindow[shiftedIndex], blockSize)
-			mean += result[resultIndex]
+			v := frequencyDomain[i] * complex128(filterWindow[shiftedIndex])
+			result[resultIndex] += real(v)*real(v) + imag(v)*imag(v)
 		}
 	}
-	mean /= float64(sliceLength)
+}
 
-	return result, mean
+// powerToDBm fills the given result with the average of the given accumulated power in dBm and returns the mean value.
+func powerToDBm(result []float64, power []float64, count int, blockSize int) float64 {
+	mean := 0.0
+	for i, p := range power {
+		result[i] = 10.0 * math.Log10(20.0*(p/float64(count))/math.Pow(float64(blockSize), 2))
+		mean += result[i]
+	}
+	mean /= float64(len(result))
+	return mean
 }
 
-func peaks(fft []float64, envelope []float64, mean float64) ([]core.PeakIndexRange, float64) {
+// peaks finds the peaks in the given spectrum that stand out from the noise floor. A peak starts where the envelope
+// rises by more than Δthreshold dB, peaks narrower than minWidth bins are ignored. The SNR of each peak is relative to
+// the given noise floor.
+func peaks(fft []float64, envelope []float64, noiseFloor float64, σ float64, Δthreshold float64, minWidth int) ([]core.PeakIndexRange, float64) {
 	if len(fft) == 0 {
 		return []core.PeakIndexRange{}, 0
 	}
 
-	sum := 0.0
-	for _, p := range fft {
-		sum += math.Pow(p-mean, 2)
-	}
-	σ := math.Sqrt(sum / float64(len(fft)))
-
-	threshold := mean + σ
-	Δthreshold := 3.0 // TODO this is a config parameter for peak detection, controls sensitivity
+	threshold := noiseFloor + σ
 	startI := 0
 	max := -200.0
 	maxI := 0
@@ -437,8 +562,10 @@ func peaks(fft []float64, envelope []float64, mean float64) ([]core.PeakIndexRan
 			max = fft[i]
 			maxI = i
 		} else if inside && (((lastΔ < -Δthreshold) && (Δ > -Δthreshold)) || (wasAbove && !above)) {
-			peak := core.PeakIndexRange{From: startI, To: i - 1, Max: maxI, Value: max}
-			result = append(result, peak)
+			peak := core.PeakIndexRange{From: startI, To: i - 1, Max: maxI, Value: max, SNR: max - noiseFloor}
+			if peak.To-peak.From+1 >= minWidth {
+				result = append(result, peak)
+			}
 			inside = false
 		}
 
@@ -479,9 +606,17 @@ func sinc(x float64) float64 {
 	return math.Sin(math.Pi*x) / (math.Pi * x)
 }
 
-func fftLowpass(blockSize int, decimation int) []complex128 {
+// fftLowpass returns the frequency response of a lowpass filter for the given decimation. The single precision of complex64
+// is good enough for a filter window and halves its memory footprint.
+func fftLowpass(blockSize int, decimation int) []complex64 {
 	impulseResponse := make([]complex128, blockSize)
 	filter := firLowpass(blockSize/2+1, 1.0/float64(2*decimation))
 	copy(impulseResponse, filter)
-	return dsp.FFT(impulseResponse)
+	frequencyResponse := dsp.FFT(impulseResponse)
+
+	result := make([]complex64, len(frequencyResponse))
+	for i, v := range frequencyResponse {
+		result[i] = complex64(v)
+	}
+	return result
 }
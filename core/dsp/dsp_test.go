@@ -38,9 +38,10 @@ func TestSamplesRoundtrip(t *testing.T) {
 	defer close(stop)
 	go dsp.Run(stop)
 
-	dsp.ProcessSamples(make([]complex128, 512), core.FrequencyRange{From: 7050000, To: 7100000}, core.VFO{Frequency: 7075000})
+	dsp.ProcessSamples(make([]complex128, 512), 0.25, core.FrequencyRange{From: 7050000, To: 7100000}, core.VFO{Frequency: 7075000})
 	select {
-	case <-dsp.FFT():
+	case fft := <-dsp.FFT():
+		assert.Equal(t, 0.25, fft.Clipping)
 	case <-time.After(10 * time.Millisecond):
 		assert.Fail(t, "missing result from processing samples")
 	}
@@ -81,7 +82,7 @@ func TestShift(t *testing.T) {
 	for f := -0.5; f <= 0.5; f += 0.001 {
 		samples := tone(blockSize, f)
 
-		shifted := shift(samples, -f)
+		shifted := shift(make([]complex128, blockSize), samples, -f)
 		fft := dsp.FFT(shifted)
 		magnitudes := make([]float64, len(fft))
 		for i, c := range fft {
@@ -136,21 +137,36 @@ func TestFilter(t *testing.T) {
 	}
 	for _, tC := range testCases {
 		t.Run(fmt.Sprintf("%v", tC.samples), func(t *testing.T) {
-			actual := filter(tC.samples, tC.filter)
+			actual := filter(make([]complex128, len(tC.samples)), tC.samples, tC.filter)
 			assert.Equal(t, tC.expected, actual)
 		})
 	}
 }
 
+func TestFilterReusesResult(t *testing.T) {
+	result := []complex128{99, 99, 99}
+
+	actual := filter(result, []complex128{1, 2, 3}, []complex128{11, 7})
+
+	assert.Equal(t, []complex128{11, 29, 47}, actual)
+	assert.Equal(t, &result[0], &actual[0])
+}
+
 func BenchmarkFilter(b *testing.B) {
+	samples := []complex128{1, 2, 3, 4, 5}
+	result := make([]complex128, len(samples))
+	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
-		filter([]complex128{1, 2, 3, 4, 5}, []complex128{11, 7})
+		filter(result, samples, []complex128{11, 7})
 	}
 }
 
 func BenchmarkShiftAndFilter(b *testing.B) {
+	samples := []complex128{1, 2, 3, 4, 5}
+	result := make([]complex128, len(samples))
+	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
-		shiftAndFilter([]complex128{1, 2, 3, 4, 5}, 0.1, []complex128{11, 7})
+		shiftAndFilter(result, samples, 0.1, []complex128{11, 7})
 	}
 }
 
@@ -179,21 +195,27 @@ func TestDecimate(t *testing.T) {
 	for _, tC := range testCases {
 		t.Run(fmt.Sprintf("%v", tC.samples), func(t *testing.T) {
 			filter := []complex128{1}
-			actual := decimate(tC.samples, tC.decimation, filter)
+			actual := decimate(make([]complex128, len(tC.samples)/tC.decimation), tC.samples, tC.decimation, filter)
 			assert.Equal(t, tC.expected, actual)
 		})
 	}
 }
 
 func BenchmarkDecimate(b *testing.B) {
+	samples := []complex128{1, 2, 3, 4, 5, 6, 7, 8}
+	result := make([]complex128, len(samples)/2)
+	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
-		decimate([]complex128{1, 2, 3, 4, 5, 6, 7, 8}, 2, []complex128{11, 7})
+		decimate(result, samples, 2, []complex128{11, 7})
 	}
 }
 
 func BenchmarkShiftAndDecimate(b *testing.B) {
+	samples := []complex128{1, 2, 3, 4, 5, 6, 7, 8}
+	result := make([]complex128, len(samples)/2)
+	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
-		shiftAndDecimate([]complex128{1, 2, 3, 4, 5, 6, 7, 8}, 0.1, 2, []complex128{11, 7})
+		shiftAndDecimate(result, samples, 0.1, 2, []complex128{11, 7})
 	}
 }
 
@@ -228,6 +250,128 @@ func TestFindBlocksize(t *testing.T) {
 	}
 }
 
+func TestSupportedOverlap(t *testing.T) {
+	assert.Equal(t, 0.0, supportedOverlap(-1))
+	assert.Equal(t, 0.0, supportedOverlap(0.3))
+	assert.Equal(t, 0.5, supportedOverlap(0.5))
+	assert.Equal(t, 0.5, supportedOverlap(0.6))
+	assert.Equal(t, 0.75, supportedOverlap(0.75))
+	assert.Equal(t, 0.75, supportedOverlap(0.9))
+}
+
+func TestStreamingProcessesAllSamples(t *testing.T) {
+	dsp := New(8192, 70000, -256)
+	dsp.SetOverlap(0.75, 4)
+	(<-dsp.command)()
+	fftRange := core.FrequencyRange{From: 70000, To: 72048}
+	vfo := core.VFO{Frequency: 71024}
+
+	for i := 0; i < 3; i++ {
+		dsp.doWork(work{tone(512, 0.1), 0, fftRange, vfo})
+	}
+
+	assert.Equal(t, 9, dsp.frameCount, "one frame for the first block, four frames for every further block")
+	assert.Equal(t, 0, len(dsp.FFT()))
+
+	dsp.doWork(work{tone(512, 0.1), 0, fftRange, vfo})
+
+	assert.Equal(t, 0, dsp.frameCount)
+	assert.Equal(t, 1, len(dsp.FFT()))
+}
+
+func TestWelchAverageKeepsLevel(t *testing.T) {
+	fftRange := core.FrequencyRange{From: 70000, To: 72048}
+	vfo := core.VFO{Frequency: 71024}
+
+	single := New(8192, 70000, -256)
+	single.averaging = core.Averaging{Mode: core.AveragingNone}
+	single.doWork(work{tone(512, 0.125), 0, fftRange, vfo})
+	expected := <-single.FFT()
+
+	streaming := New(8192, 70000, -256)
+	streaming.averaging = core.Averaging{Mode: core.AveragingNone}
+	streaming.overlap = 0.5
+	streaming.fftPerSecond = 8
+	for i := 0; i < 2; i++ {
+		streaming.doWork(work{tone(512, 0.125), 0, fftRange, vfo})
+	}
+	actual := <-streaming.FFT()
+
+	maxIndex := func(values []float64) int {
+		result := 0
+		for i, v := range values {
+			if v > values[result] {
+				result = i
+			}
+		}
+		return result
+	}
+	assert.Equal(t, maxIndex(expected.Data), maxIndex(actual.Data))
+	assert.InDelta(t, expected.Data[maxIndex(expected.Data)], actual.Data[maxIndex(actual.Data)], 0.01)
+}
+
+func TestSetAveragingReplacesSmoother(t *testing.T) {
+	fftRange := core.FrequencyRange{From: 70000, To: 72048}
+	vfo := core.VFO{Frequency: 71024}
+	dsp := New(8192, 70000, -256)
+	dsp.doWork(work{tone(512, 0.125), 0, fftRange, vfo})
+	<-dsp.FFT()
+
+	dsp.SetAveraging(core.Averaging{Mode: core.AveragingMaxHold})
+	(<-dsp.command)()
+
+	assert.IsType(t, passThrough{}, dsp.smoother)
+	if assert.NotNil(t, dsp.hold) {
+		assert.Equal(t, dsp.outputBlockSize, len(dsp.hold.current))
+	}
+	dsp.doWork(work{tone(512, 0.125), 0, fftRange, vfo})
+	assert.NotNil(t, (<-dsp.FFT()).Hold)
+}
+
+func TestResetAveragingKeepsHold(t *testing.T) {
+	fftRange := core.FrequencyRange{From: 70000, To: 72048}
+	vfo := core.VFO{Frequency: 71024}
+	dsp := New(8192, 70000, -256)
+	dsp.averaging = core.Averaging{Mode: core.AveragingMaxHold}
+	dsp.overlap = 0.5
+	dsp.fftPerSecond = 8
+
+	dsp.doWork(work{tone(512, 0.125), 0, fftRange, vfo})
+	dsp.doWork(work{tone(512, 0.125), 0, fftRange, vfo})
+	before := <-dsp.FFT()
+	assert.NotNil(t, before.Hold)
+
+	dsp.resetAveraging()
+
+	assert.Equal(t, 0, dsp.frameCount)
+	assert.Equal(t, 0, dsp.streamFill)
+	assert.Equal(t, 0, len(dsp.FFT()))
+	dsp.doWork(work{make([]complex128, 512), 0, fftRange, vfo})
+	dsp.doWork(work{make([]complex128, 512), 0, fftRange, vfo})
+	after := <-dsp.FFT()
+	assert.Equal(t, before.Hold, after.Hold, "the silence after the reset must not lower the max hold")
+}
+
+func BenchmarkProcessBlock(b *testing.B) {
+	dsp := New(1800000, 67899000, -450000)
+	samples := tones(65536, 0.1, -0.27, 0.4)
+	fftRange := core.FrequencyRange{From: 7000000, To: 7200000}
+	vfo := core.VFO{Frequency: 7100000}
+	process := func() {
+		dsp.ProcessSamples(samples, 0, fftRange, vfo)
+		dsp.doWork(<-dsp.workInput)
+		<-dsp.FFT()
+	}
+	process() // the first block configures the DSP, only the following blocks are measured
+
+	b.SetBytes(int64(len(samples)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		process()
+	}
+}
+
 func peakIndex(frequencyRate float64, blockSize int) int {
 	peak := int(math.Round(frequencyRate * float64(blockSize)))
 	if peak < 0 {
@@ -0,0 +1,131 @@
+package dsp
+
+import (
+	"io"
+	"log"
+	"time"
+
+	"github.com/ftl/panacotta/core/iq"
+)
+
+// NewFileInput returns a new SamplesInput that replays the samples of the given IQ file in real time. For raw files without
+// sidecar, the given sample rate is used. If loop is true, the file is replayed endlessly.
+func NewFileInput(filename string, blockSize int, sampleRate int, loop bool) (*FileInput, error) {
+	reader, err := iq.Open(filename, sampleRate)
+	if err != nil {
+		return nil, err
+	}
+
+	result := FileInput{
+		reader:  reader,
+		samples: make(chan []complex128, 1),
+		seek:    make(chan time.Duration, 1),
+		done:    make(chan struct{}),
+	}
+	log.Printf("FileInput %s: %s, %d samples/s, %v", filename, reader.Format(), reader.SampleRate(), result.duration(reader.Length()))
+
+	go result.run(blockSize, loop)
+
+	return &result, nil
+}
+
+type FileInput struct {
+	reader  *iq.Reader
+	samples chan []complex128
+	seek    chan time.Duration
+	done    chan struct{}
+}
+
+func (i *FileInput) run(blockSize int, loop bool) {
+	defer log.Print("FileInput shutdown")
+	defer i.reader.Close()
+
+	interval := i.duration(int64(blockSize))
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+
+	finished := false
+	for {
+		select {
+		case <-tick.C:
+			if finished {
+				continue
+			}
+			var nextBlock []complex128
+			nextBlock, finished = i.nextBlock(blockSize, loop)
+			select {
+			case i.samples <- nextBlock:
+			default:
+				log.Print("FileInput buffer overflow, dropping samples")
+			}
+		case offset := <-i.seek:
+			position := int64(offset.Seconds() * float64(i.reader.SampleRate()))
+			if position < 0 {
+				position = 0
+			}
+			if position > i.reader.Length() {
+				position = i.reader.Length()
+			}
+			err := i.reader.SetPosition(position)
+			if err != nil {
+				log.Printf("FileInput cannot seek to %v: %v", offset, err)
+				continue
+			}
+			finished = false
+		case <-i.done:
+			close(i.samples)
+			return
+		}
+	}
+}
+
+// nextBlock reads the next block of samples. At the end of the file, the block is either filled from the beginning of the
+// file or padded with zeros, if looping is not wanted.
+func (i *FileInput) nextBlock(blockSize int, loop bool) ([]complex128, bool) {
+	result := make([]complex128, blockSize)
+	filled := 0
+	rewound := false
+	for filled < blockSize {
+		n, err := i.reader.Read(result[filled:])
+		filled += n
+		if err == io.EOF {
+			if !loop || (rewound && n == 0) {
+				return result, true
+			}
+			err = i.reader.SetPosition(0)
+			rewound = true
+		}
+		if err != nil {
+			log.Printf("FileInput cannot read samples: %v", err)
+			return result, true
+		}
+	}
+	return result, false
+}
+
+func (i *FileInput) duration(samples int64) time.Duration {
+	return time.Duration(float64(samples) / float64(i.reader.SampleRate()) * float64(time.Second))
+}
+
+func (i *FileInput) Samples() <-chan []complex128 {
+	return i.samples
+}
+
+// SampleRate of the replayed file.
+func (i *FileInput) SampleRate() int {
+	return i.reader.SampleRate()
+}
+
+// Seek to the given offset from the beginning of the file.
+func (i *FileInput) Seek(offset time.Duration) {
+	select {
+	case i.seek <- offset:
+	default:
+		log.Print("FileInput.Seek hangs")
+	}
+}
+
+func (i *FileInput) Close() error {
+	close(i.done)
+	return nil
+}
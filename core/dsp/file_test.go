@@ -0,0 +1,85 @@
+package dsp
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ftl/panacotta/core/iq"
+)
+
+func TestFileInputLoops(t *testing.T) {
+	dir, err := ioutil.TempDir("", "panacotta-dsp")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "tone.cf32")
+	w, err := iq.Create(filename, iq.Metadata{Format: iq.FormatCF32, SampleRate: 16000})
+	require.NoError(t, err)
+	require.NoError(t, w.Write([]complex128{1, 2, 3}))
+	require.NoError(t, w.Close())
+
+	input, err := NewFileInput(filename, 4, 0, true)
+	require.NoError(t, err)
+	defer input.Close()
+
+	assert.Equal(t, 16000, input.SampleRate())
+	assert.Equal(t, []complex128{1, 2, 3, 1}, nextSamples(t, input))
+	assert.Equal(t, []complex128{2, 3, 1, 2}, nextSamples(t, input))
+}
+
+func TestFileInputStopsAtEnd(t *testing.T) {
+	dir, err := ioutil.TempDir("", "panacotta-dsp")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "tone.cf32")
+	w, err := iq.Create(filename, iq.Metadata{Format: iq.FormatCF32, SampleRate: 16000})
+	require.NoError(t, err)
+	require.NoError(t, w.Write([]complex128{1, 2, 3}))
+	require.NoError(t, w.Close())
+
+	input, err := NewFileInput(filename, 2, 0, false)
+	require.NoError(t, err)
+	defer input.Close()
+
+	assert.Equal(t, []complex128{1, 2}, nextSamples(t, input))
+	assert.Equal(t, []complex128{3, 0}, nextSamples(t, input))
+	select {
+	case <-input.Samples():
+		assert.Fail(t, "no more samples expected at the end of the file")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	input.Seek(0)
+	assert.Equal(t, []complex128{1, 2}, nextSamples(t, input))
+}
+
+func TestFileInputDecodesRawDumpLikeLiveInput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "panacotta-dsp")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "dump.cu8")
+	raw := []byte{0, 255, 127, 128, 254, 1, 200, 30}
+	require.NoError(t, ioutil.WriteFile(filename, raw, 0644))
+
+	input, err := NewFileInput(filename, 4, 16000, false)
+	require.NoError(t, err)
+	defer input.Close()
+
+	assert.Equal(t, ConvertUint8Samples(raw), nextSamples(t, input))
+}
+
+func nextSamples(t *testing.T, input *FileInput) []complex128 {
+	t.Helper()
+	select {
+	case samples := <-input.Samples():
+		return samples
+	case <-time.After(100 * time.Millisecond):
+		assert.Fail(t, "missing samples")
+		return nil
+	}
+}
@@ -0,0 +1,53 @@
+package dsp
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	noiseFloorIterations = 3
+	noiseOutlierFactor   = 3.0    // bins further than this number of σ above the noise floor are excluded
+	madToSigma           = 1.4826 // scales the median absolute deviation to the standard deviation of normal noise
+)
+
+// noiseFloor estimates the level and the spread of the noise in the given spectrum. The noise floor is the median of
+// all bins that do not stand out from the noise, the spread is derived from the median absolute deviation. Strong
+// signals are excluded iteratively, so a few carriers do not drag the estimate upwards.
+func noiseFloor(spectrum []float64) (floor float64, σ float64) {
+	if len(spectrum) == 0 {
+		return 0, 0
+	}
+
+	values := make([]float64, len(spectrum))
+	copy(values, spectrum)
+	sort.Float64s(values)
+	deviations := make([]float64, len(values))
+
+	for i := 0; i < noiseFloorIterations; i++ {
+		floor = median(values)
+		deviations = deviations[:len(values)]
+		for j, v := range values {
+			deviations[j] = math.Abs(v - floor)
+		}
+		sort.Float64s(deviations)
+		σ = madToSigma * median(deviations)
+
+		limit := floor + noiseOutlierFactor*σ
+		count := sort.SearchFloat64s(values, math.Nextafter(limit, math.Inf(1)))
+		if count == len(values) || count == 0 {
+			break
+		}
+		values = values[:count]
+	}
+	return floor, σ
+}
+
+// median of the given sorted values.
+func median(values []float64) float64 {
+	n := len(values)
+	if n%2 == 1 {
+		return values[n/2]
+	}
+	return (values[n/2-1] + values[n/2]) / 2
+}
@@ -0,0 +1,77 @@
+package dsp
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNoiseFloor(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	spectrum := make([]float64, 1024)
+	for i := range spectrum {
+		spectrum[i] = -100 + random.NormFloat64()*2
+	}
+	quietFloor, quietσ := noiseFloor(spectrum)
+
+	for _, carrier := range []int{100, 300, 301, 302, 700, 900} {
+		spectrum[carrier] = -20
+	}
+	floor, σ := noiseFloor(spectrum)
+
+	assert.InDelta(t, -100, quietFloor, 0.5)
+	assert.InDelta(t, 2, quietσ, 0.2)
+	assert.InDelta(t, quietFloor, floor, 0.1)
+	assert.InDelta(t, quietσ, σ, 0.1)
+}
+
+func TestNoiseFloorEmpty(t *testing.T) {
+	floor, σ := noiseFloor([]float64{})
+
+	assert.Equal(t, 0.0, floor)
+	assert.Equal(t, 0.0, σ)
+}
+
+func TestPeakSNR(t *testing.T) {
+	spectrum := make([]float64, 64)
+	for i := range spectrum {
+		spectrum[i] = -100
+	}
+	for i, v := range []float64{-90, -70, -60, -70, -90} {
+		spectrum[30+i] = v
+	}
+	_, envelope := centeredSlidingWindowAverageAndSigmaEnvelope(spectrum, 9)
+
+	actual, threshold := peaks(spectrum, envelope, -100, 1, 3, 0)
+
+	assert.Equal(t, -99.0, threshold)
+	assert.NotEmpty(t, actual)
+	maxSNR := 0.0
+	for _, peak := range actual {
+		assert.Equal(t, peak.Value+100, peak.SNR)
+		maxSNR = math.Max(maxSNR, peak.SNR)
+	}
+	assert.Equal(t, 40.0, maxSNR)
+}
+
+func TestPeakMinWidth(t *testing.T) {
+	spectrum := make([]float64, 64)
+	for i := range spectrum {
+		spectrum[i] = -100
+	}
+	spectrum[20] = -60
+	for i, v := range []float64{-90, -70, -60, -60, -60, -60, -70, -90} {
+		spectrum[40+i] = v
+	}
+	_, envelope := centeredSlidingWindowAverageAndSigmaEnvelope(spectrum, 9)
+
+	all, _ := peaks(spectrum, envelope, -100, 1, 3, 0)
+	wide, _ := peaks(spectrum, envelope, -100, 1, 3, 8)
+
+	assert.True(t, len(wide) < len(all), "%d < %d", len(wide), len(all))
+	for _, peak := range wide {
+		assert.True(t, peak.To-peak.From+1 >= 8)
+	}
+}
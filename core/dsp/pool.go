@@ -0,0 +1,37 @@
+package dsp
+
+// NewBlockPool returns a new pool that keeps up to the given number of released blocks for reuse.
+func NewBlockPool(capacity int) *BlockPool {
+	return &BlockPool{
+		free: make(chan []complex128, capacity),
+	}
+}
+
+// BlockPool provides reusable blocks of samples. Every block taken with Get should be handed back with Put as soon as
+// it is not used anymore. A pool is safe for concurrent use.
+type BlockPool struct {
+	free chan []complex128
+}
+
+// Get a block with the given number of samples. The content of the block is undefined.
+func (p *BlockPool) Get(size int) []complex128 {
+	select {
+	case block := <-p.free:
+		if cap(block) >= size {
+			return block[:size]
+		}
+	default:
+	}
+	return make([]complex128, size)
+}
+
+// Put the given block back into the pool. The block must not be used anymore afterwards.
+func (p *BlockPool) Put(block []complex128) {
+	if block == nil {
+		return
+	}
+	select {
+	case p.free <- block:
+	default:
+	}
+}
@@ -0,0 +1,32 @@
+package dsp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlockPool(t *testing.T) {
+	pool := NewBlockPool(1)
+
+	block := pool.Get(16)
+	assert.Equal(t, 16, len(block))
+
+	pool.Put(block)
+	reused := pool.Get(8)
+	assert.Equal(t, 8, len(reused))
+	assert.Equal(t, &block[0], &reused[0], "block should be reused")
+
+	pool.Put(reused)
+	larger := pool.Get(32)
+	assert.Equal(t, 32, len(larger))
+}
+
+func TestBlockPoolDropsSurplusBlocks(t *testing.T) {
+	pool := NewBlockPool(1)
+
+	pool.Put(make([]complex128, 4))
+	pool.Put(make([]complex128, 4))
+
+	assert.Equal(t, 1, len(pool.free))
+}
@@ -0,0 +1,59 @@
+package dsp
+
+import (
+	"math"
+	"math/bits"
+)
+
+// newFFTPlan returns a plan to calculate in-place FFTs of the given size. The size must be a power of two, otherwise
+// the result is nil.
+func newFFTPlan(size int) *fftPlan {
+	if size < 2 || size&(size-1) != 0 {
+		return nil
+	}
+
+	result := &fftPlan{
+		size:     size,
+		twiddles: make([]complex128, size/2),
+		reversed: make([]int, size),
+	}
+	for i := range result.twiddles {
+		ω := -2 * math.Pi * float64(i) / float64(size)
+		result.twiddles[i] = complex(math.Cos(ω), math.Sin(ω))
+	}
+	shift := uint(bits.UintSize - bits.TrailingZeros(uint(size)))
+	for i := range result.reversed {
+		result.reversed[i] = int(bits.Reverse(uint(i)) >> shift)
+	}
+
+	return result
+}
+
+// fftPlan calculates the FFT of blocks with a fixed size in place, without allocating any memory. The result is the same
+// as from go-dsp's FFT.
+type fftPlan struct {
+	size     int
+	twiddles []complex128
+	reversed []int
+}
+
+func (p *fftPlan) transform(samples []complex128) {
+	for i, j := range p.reversed {
+		if i < j {
+			samples[i], samples[j] = samples[j], samples[i]
+		}
+	}
+
+	for length := 2; length <= p.size; length <<= 1 {
+		half := length / 2
+		step := p.size / length
+		for start := 0; start < p.size; start += length {
+			for k := 0; k < half; k++ {
+				t := p.twiddles[k*step] * samples[start+k+half]
+				u := samples[start+k]
+				samples[start+k] = u + t
+				samples[start+k+half] = u - t
+			}
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package dsp
+
+import (
+	"fmt"
+	"testing"
+
+	dsp "github.com/mjibson/go-dsp/fft"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFFTPlanOnlyForPowersOfTwo(t *testing.T) {
+	assert.Nil(t, newFFTPlan(0))
+	assert.Nil(t, newFFTPlan(1))
+	assert.Nil(t, newFFTPlan(12))
+	assert.NotNil(t, newFFTPlan(16))
+}
+
+func TestFFTPlanLikeGoDSP(t *testing.T) {
+	for _, blockSize := range []int{2, 16, 1024} {
+		t.Run(fmt.Sprintf("%d", blockSize), func(t *testing.T) {
+			samples := tones(blockSize, 0.1, -0.27, 0.4)
+			expected := dsp.FFT(samples)
+
+			newFFTPlan(blockSize).transform(samples)
+
+			for i := range expected {
+				assert.InDelta(t, real(expected[i]), real(samples[i]), 1e-9, "real %d", i)
+				assert.InDelta(t, imag(expected[i]), imag(samples[i]), 1e-9, "imag %d", i)
+			}
+		})
+	}
+}
+
+func BenchmarkGoDSPFFT(b *testing.B) {
+	samples := tones(65536, 0.1, -0.27, 0.4)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		dsp.FFT(samples)
+	}
+}
+
+func BenchmarkFFTPlan(b *testing.B) {
+	samples := tones(65536, 0.1, -0.27, 0.4)
+	plan := newFFTPlan(len(samples))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		plan.transform(samples)
+	}
+}
@@ -0,0 +1,94 @@
+package dsp
+
+import (
+	"math"
+
+	"github.com/mjibson/go-dsp/window"
+
+	"github.com/ftl/panacotta/core"
+)
+
+// fftWindow returns the values of the given window with the given size, corrected for the coherent gain of the window.
+// This keeps the level of a sinusoidal signal the same, independent of the selected window.
+func fftWindow(w core.FFTWindow, size int) []float64 {
+	var result []float64
+	switch w.Function {
+	case core.WindowHann:
+		result = window.Hann(size)
+	case core.WindowBlackman:
+		result = window.Blackman(size)
+	case core.WindowBlackmanHarris:
+		result = blackmanHarris(size)
+	case core.WindowFlatTop:
+		result = window.FlatTop(size)
+	case core.WindowKaiser:
+		result = kaiser(size, w.KaiserBeta)
+	default:
+		result = window.Rectangular(size)
+	}
+
+	sum := 0.0
+	for _, v := range result {
+		sum += v
+	}
+	if sum == 0 {
+		return result
+	}
+	coherentGain := sum / float64(size)
+	for i := range result {
+		result[i] /= coherentGain
+	}
+	return result
+}
+
+// blackmanHarris returns a 4-term Blackman-Harris window with the given size.
+func blackmanHarris(size int) []float64 {
+	const (
+		a0 = 0.35875
+		a1 = 0.48829
+		a2 = 0.14128
+		a3 = 0.01168
+	)
+	result := make([]float64, size)
+	if size == 1 {
+		result[0] = 1
+		return result
+	}
+	n := float64(size - 1)
+	for i := range result {
+		x := 2 * math.Pi * float64(i) / n
+		result[i] = a0 - a1*math.Cos(x) + a2*math.Cos(2*x) - a3*math.Cos(3*x)
+	}
+	return result
+}
+
+// kaiser returns a Kaiser window with the given size and shape parameter β.
+func kaiser(size int, β float64) []float64 {
+	result := make([]float64, size)
+	if size == 1 {
+		result[0] = 1
+		return result
+	}
+	n := float64(size - 1)
+	norm := besselI0(β)
+	for i := range result {
+		x := 2*float64(i)/n - 1
+		result[i] = besselI0(β*math.Sqrt(1-x*x)) / norm
+	}
+	return result
+}
+
+// besselI0 is the zeroth order modified Bessel function of the first kind.
+func besselI0(x float64) float64 {
+	result := 1.0
+	term := 1.0
+	halfX := x / 2
+	for k := 1; k < 50; k++ {
+		term *= (halfX / float64(k)) * (halfX / float64(k))
+		result += term
+		if term < result*1e-12 {
+			break
+		}
+	}
+	return result
+}
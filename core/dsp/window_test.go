@@ -0,0 +1,45 @@
+package dsp
+
+import (
+	"fmt"
+	"math/cmplx"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ftl/panacotta/core"
+)
+
+func TestFFTWindowCoherentGain(t *testing.T) {
+	blockSize := 1024
+	for f := core.WindowRectangular; f <= core.WindowKaiser; f++ {
+		t.Run(f.String(), func(t *testing.T) {
+			samples := tone(blockSize, 0.125)
+			applyWindow(samples, fftWindow(core.FFTWindow{Function: f, KaiserBeta: 8.6}, blockSize))
+
+			newFFTPlan(blockSize).transform(samples)
+
+			level := fftValue2dBm(samples[peakIndex(0.125, blockSize)], blockSize)
+			assert.InDelta(t, fftValue2dBm(complex(float64(blockSize), 0), blockSize), level, 0.01)
+		})
+	}
+}
+
+func TestBlackmanHarrisSidelobes(t *testing.T) {
+	blockSize := 1024
+	samples := tone(blockSize, 0.1234)
+	applyWindow(samples, fftWindow(core.FFTWindow{Function: core.WindowBlackmanHarris}, blockSize))
+
+	newFFTPlan(blockSize).transform(samples)
+
+	peak := peakIndex(0.1234, blockSize)
+	for i := peak + 8; i < blockSize/2; i++ {
+		assert.True(t, cmplx.Abs(samples[i]) < cmplx.Abs(samples[peak])*1e-4, fmt.Sprintf("bin %d", i))
+	}
+}
+
+func TestBesselI0(t *testing.T) {
+	assert.Equal(t, 1.0, besselI0(0))
+	assert.InDelta(t, 1.2660658777520082, besselI0(1), 1e-12)
+	assert.InDelta(t, 27.239871823604442, besselI0(5), 1e-9)
+}
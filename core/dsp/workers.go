@@ -0,0 +1,163 @@
+package dsp
+
+import (
+	"runtime"
+
+	dsp "github.com/mjibson/go-dsp/fft"
+)
+
+// frameJob calculates the power spectrum of one frame. Jobs are independent of each other and may be processed in
+// parallel, everything a job needs is captured when it is dispatched.
+type frameJob struct {
+	seq        uint64
+	generation int
+	emit       bool // the accumulated power is emitted after this frame
+
+	samples      []complex128
+	power        []float64
+	plan         *fftPlan
+	fftWindow    []float64
+	filterWindow []complex64
+	offsetRate   float64
+}
+
+func (j *frameJob) process() {
+	applyWindow(j.samples, j.fftWindow)
+	frequencyDomain := transform(j.plan, j.samples)
+	for i := range j.power {
+		j.power[i] = 0
+	}
+	fftSlice(j.power, frequencyDomain, j.offsetRate, j.filterWindow)
+}
+
+// transform calculates the FFT of the given samples. If a plan is given, the FFT is calculated in place.
+func transform(plan *fftPlan, samples []complex128) []complex128 {
+	if plan == nil {
+		return dsp.FFT(samples)
+	}
+	plan.transform(samples)
+	return samples
+}
+
+func runFrameWorker(jobs <-chan *frameJob, results chan<- *frameJob) {
+	for job := range jobs {
+		job.process()
+		results <- job
+	}
+}
+
+// SetWorkers sets the number of workers that process frames in parallel. With only one worker, the frames are processed
+// on the DSP's goroutine. A count of 0 or less uses one worker per CPU.
+func (d *DSP) SetWorkers(count int) {
+	d.q(func() {
+		d.setWorkers(count)
+	})
+}
+
+func (d *DSP) setWorkers(count int) {
+	if count <= 0 {
+		count = runtime.NumCPU()
+	}
+
+	d.drainFrames()
+	d.stopWorkers()
+
+	d.pending = make([]*frameJob, 2*count)
+	if count == 1 {
+		return
+	}
+	d.jobs = make(chan *frameJob, len(d.pending))
+	d.results = make(chan *frameJob, len(d.pending))
+	for i := 0; i < count; i++ {
+		go runFrameWorker(d.jobs, d.results)
+	}
+}
+
+func (d *DSP) stopWorkers() {
+	if d.jobs == nil {
+		return
+	}
+	close(d.jobs)
+	d.jobs = nil
+	d.results = nil
+}
+
+// dispatchFrame hands the given frame over to the workers. The frame must be taken from the DSP's block pool, it is
+// released when the frame is processed.
+func (d *DSP) dispatchFrame(frame []complex128, emit bool) {
+	job := d.newJob()
+	job.seq = d.dispatchedSeq
+	job.generation = d.generation
+	job.emit = emit
+	job.samples = frame
+	job.plan = d.fftPlan
+	job.fftWindow = d.fftWindow
+	job.filterWindow = d.filterWindow
+	job.offsetRate = d.fftRangeOffsetRate
+	if len(job.power) != d.outputBlockSize {
+		job.power = make([]float64, d.outputBlockSize)
+	}
+	d.dispatchedSeq++
+
+	if d.jobs == nil {
+		job.process()
+		d.collect(job)
+		return
+	}
+
+	// the ring of pending frames must not wrap around before the oldest frame is collected
+	for job.seq-d.collectedSeq >= uint64(len(d.pending)) {
+		d.inFlight--
+		d.collect(<-d.results)
+	}
+	d.inFlight++
+	d.jobs <- job
+}
+
+// collect a processed frame. The frames are accumulated in the order they were dispatched.
+func (d *DSP) collect(job *frameJob) {
+	d.pending[job.seq%uint64(len(d.pending))] = job
+	for {
+		index := d.collectedSeq % uint64(len(d.pending))
+		next := d.pending[index]
+		if next == nil || next.seq != d.collectedSeq {
+			return
+		}
+		d.pending[index] = nil
+		d.collectedSeq++
+
+		if next.generation == d.generation {
+			for i, p := range next.power {
+				d.power[i] += p
+			}
+			d.frameCount++
+		}
+		if next.emit {
+			d.emitFFT()
+		}
+		d.releaseJob(next)
+	}
+}
+
+// drainFrames waits until all dispatched frames are collected.
+func (d *DSP) drainFrames() {
+	for d.inFlight > 0 {
+		d.inFlight--
+		d.collect(<-d.results)
+	}
+}
+
+func (d *DSP) newJob() *frameJob {
+	if len(d.freeJobs) == 0 {
+		return new(frameJob)
+	}
+	result := d.freeJobs[len(d.freeJobs)-1]
+	d.freeJobs = d.freeJobs[:len(d.freeJobs)-1]
+	return result
+}
+
+func (d *DSP) releaseJob(job *frameJob) {
+	d.blocks.Put(job.samples)
+	job.samples = nil
+	d.freeJobs = append(d.freeJobs, job)
+}
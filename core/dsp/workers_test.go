@@ -0,0 +1,117 @@
+package dsp
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ftl/panacotta/core"
+)
+
+func TestWorkersEmitInOrder(t *testing.T) {
+	dsp := New(8192, 70000, -256)
+	dsp.averaging = core.Averaging{Mode: core.AveragingNone}
+	dsp.fft = make(chan core.FFT, 100)
+	dsp.setWorkers(4)
+	defer dsp.stopWorkers()
+	fftRange := core.FrequencyRange{From: 70000, To: 72048}
+	vfo := core.VFO{Frequency: 71280}
+
+	blockCount := 20
+	for i := 0; i < blockCount; i++ {
+		rate := 0.4 + 0.2*float64(i)/float64(blockCount) // the center of the slice is at half the sample rate
+		dsp.doWork(work{tone(512, rate), 0, fftRange, vfo})
+	}
+	dsp.drainFrames()
+
+	assert.Equal(t, blockCount, len(dsp.fft))
+	lastPeak := -1
+	for i := 0; i < blockCount; i++ {
+		fft := <-dsp.fft
+		peak := 0
+		for j, v := range fft.Data {
+			if v > fft.Data[peak] {
+				peak = j
+			}
+		}
+		assert.True(t, peak > lastPeak, "FFT %d out of order: %d <= %d", i, peak, lastPeak)
+		lastPeak = peak
+	}
+}
+
+func TestSetWorkers(t *testing.T) {
+	dsp := New(8192, 70000, -256)
+
+	dsp.setWorkers(1)
+	assert.Nil(t, dsp.jobs)
+
+	dsp.setWorkers(3)
+	assert.NotNil(t, dsp.jobs)
+	assert.Equal(t, 6, len(dsp.pending))
+
+	dsp.setWorkers(0)
+	assert.NotNil(t, dsp.pending)
+
+	dsp.stopWorkers()
+	assert.Nil(t, dsp.jobs)
+}
+
+func TestSetAveragingWithFramesInFlight(t *testing.T) {
+	dsp := New(8192, 70000, -256)
+	dsp.fft = make(chan core.FFT, 100)
+	dsp.setWorkers(4)
+	defer dsp.stopWorkers()
+	fftRange := core.FrequencyRange{From: 70000, To: 72048}
+	vfo := core.VFO{Frequency: 71024}
+
+	modes := []core.AveragingMode{core.AveragingMaxHold, core.AveragingExponential, core.AveragingNone, core.AveragingMoving}
+	for _, mode := range modes {
+		for i := 0; i < 6; i++ {
+			dsp.doWork(work{tone(512, 0.125), 0, fftRange, vfo})
+		}
+		dsp.SetAveraging(core.Averaging{Mode: mode, Depth: 3, Alpha: 0.5})
+		(<-dsp.command)()
+
+		assert.Equal(t, 0, dsp.inFlight, "mode %v", mode)
+		assert.Equal(t, mode, dsp.averaging.Mode)
+	}
+	dsp.doWork(work{tone(512, 0.125), 0, fftRange, vfo})
+	dsp.drainFrames()
+
+	assert.Equal(t, 6*len(modes)+1, len(dsp.fft))
+}
+
+func BenchmarkStreamingWorkers(b *testing.B) {
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			dsp := New(1800000, 67899000, -450000)
+			dsp.overlap = 0.75
+			dsp.fftPerSecond = 25
+			dsp.setWorkers(workers)
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for range dsp.fft {
+				}
+			}()
+			samples := tones(65536, 0.1, -0.27, 0.4)
+			fftRange := core.FrequencyRange{From: 7000000, To: 7200000}
+			vfo := core.VFO{Frequency: 7100000}
+
+			b.SetBytes(int64(len(samples)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				dsp.ProcessSamples(samples, 0, fftRange, vfo)
+				dsp.doWork(<-dsp.workInput)
+			}
+			dsp.drainFrames()
+			b.StopTimer()
+
+			dsp.stopWorkers()
+			close(dsp.fft)
+			<-done
+		})
+	}
+}
@@ -0,0 +1,203 @@
+// Package iaru provides the HF bandplans of all three IARU regions. The hamradio bandplan package only knows region 1,
+// the plans for region 2 and 3 are taken from the band plans published by the regions (see Region2 and Region3) and
+// reduced to the segments that are relevant for the mode indicator. All regions cover the same bands.
+package iaru
+
+import (
+	"fmt"
+
+	"github.com/ftl/hamradio"
+	"github.com/ftl/hamradio/bandplan"
+)
+
+// Bandplan returns the bandplan of the given IARU region.
+func Bandplan(region int) (bandplan.Bandplan, error) {
+	switch region {
+	case 1:
+		return bandplan.IARURegion1, nil
+	case 2:
+		return Region2, nil
+	case 3:
+		return Region3, nil
+	default:
+		return nil, fmt.Errorf("unknown IARU region %d", region)
+	}
+}
+
+// Region2 is the HF bandplan of IARU region 2 (the Americas), following the "IARU Region 2 Band Plan" for HF that is
+// published on https://www.iaru-r2.org. The 60m band follows the WRC-15 allocation of 5351.5-5366.5kHz, national
+// channels outside of this allocation are not covered.
+var Region2 = bandplan.Bandplan{
+	bandplan.Band160m: band(bandplan.Band160m, 1800, 2000,
+		portion(bandplan.ModeCW, 1800, 1840),
+		portion(bandplan.ModeDigital, 1840, 1850),
+		portion(bandplan.ModePhone, 1850, 2000),
+	),
+	bandplan.Band80m: band(bandplan.Band80m, 3500, 4000,
+		portion(bandplan.ModeCW, 3500, 3580),
+		portion(bandplan.ModeDigital, 3580, 3600),
+		portion(bandplan.ModePhone, 3600, 4000),
+		portion(bandplan.ModeContest, 3510, 3560),
+		portion(bandplan.ModeContest, 3700, 3800),
+	),
+	bandplan.Band60m: band(bandplan.Band60m, 5351.5, 5366.5,
+		portion(bandplan.ModeCW, 5351.5, 5354),
+		portion(bandplan.ModePhone, 5354, 5366),
+		portion(bandplan.ModeDigital, 5366, 5366.5),
+	),
+	bandplan.Band40m: band(bandplan.Band40m, 7000, 7300,
+		portion(bandplan.ModeCW, 7000, 7040),
+		portion(bandplan.ModeDigital, 7040, 7053),
+		portion(bandplan.ModePhone, 7053, 7300),
+		portion(bandplan.ModeContest, 7000, 7025),
+	),
+	bandplan.Band30m: band(bandplan.Band30m, 10100, 10150,
+		portion(bandplan.ModeCW, 10100, 10130),
+		portion(bandplan.ModeDigital, 10130, 10150),
+	),
+	bandplan.Band20m: band(bandplan.Band20m, 14000, 14350,
+		portion(bandplan.ModeCW, 14000, 14070),
+		portion(bandplan.ModeDigital, 14070, 14099),
+		portion(bandplan.ModeBeacon, 14099, 14101),
+		portion(bandplan.ModeDigital, 14101, 14112),
+		portion(bandplan.ModePhone, 14112, 14350),
+		portion(bandplan.ModeContest, 14000, 14060),
+		portion(bandplan.ModeContest, 14125, 14300),
+	),
+	bandplan.Band17m: band(bandplan.Band17m, 18068, 18168,
+		portion(bandplan.ModeCW, 18068, 18095),
+		portion(bandplan.ModeDigital, 18095, 18109),
+		portion(bandplan.ModeBeacon, 18109, 18111),
+		portion(bandplan.ModePhone, 18111, 18168),
+	),
+	bandplan.Band15m: band(bandplan.Band15m, 21000, 21450,
+		portion(bandplan.ModeCW, 21000, 21070),
+		portion(bandplan.ModeDigital, 21070, 21149),
+		portion(bandplan.ModeBeacon, 21149, 21151),
+		portion(bandplan.ModePhone, 21151, 21450),
+	),
+	bandplan.Band12m: band(bandplan.Band12m, 24890, 24990,
+		portion(bandplan.ModeCW, 24890, 24915),
+		portion(bandplan.ModeDigital, 24915, 24929),
+		portion(bandplan.ModeBeacon, 24929, 24931),
+		portion(bandplan.ModePhone, 24931, 24990),
+	),
+	bandplan.Band10m: band(bandplan.Band10m, 28000, 29700,
+		portion(bandplan.ModeCW, 28000, 28070),
+		portion(bandplan.ModeDigital, 28070, 28190),
+		portion(bandplan.ModeBeacon, 28190, 28225),
+		portion(bandplan.ModePhone, 28225, 29700),
+	),
+}
+
+// Region3 is the HF bandplan of IARU region 3 (Asia and Pacific), following the "IARU Region 3 Band Plan" that is
+// published on https://www.iaru-r3.org. The 60m band follows the WRC-15 allocation of 5351.5-5366.5kHz.
+var Region3 = bandplan.Bandplan{
+	bandplan.Band160m: band(bandplan.Band160m, 1800, 2000,
+		portion(bandplan.ModeCW, 1800, 1834),
+		portion(bandplan.ModeDigital, 1834, 1850),
+		portion(bandplan.ModePhone, 1850, 2000),
+	),
+	bandplan.Band80m: band(bandplan.Band80m, 3500, 3900,
+		portion(bandplan.ModeCW, 3500, 3535),
+		portion(bandplan.ModeDigital, 3535, 3600),
+		portion(bandplan.ModePhone, 3600, 3900),
+	),
+	bandplan.Band60m: band(bandplan.Band60m, 5351.5, 5366.5,
+		portion(bandplan.ModeCW, 5351.5, 5354),
+		portion(bandplan.ModePhone, 5354, 5366),
+		portion(bandplan.ModeDigital, 5366, 5366.5),
+	),
+	bandplan.Band40m: band(bandplan.Band40m, 7000, 7300,
+		portion(bandplan.ModeCW, 7000, 7030),
+		portion(bandplan.ModeDigital, 7030, 7040),
+		portion(bandplan.ModePhone, 7040, 7300),
+	),
+	bandplan.Band30m: band(bandplan.Band30m, 10100, 10150,
+		portion(bandplan.ModeCW, 10100, 10130),
+		portion(bandplan.ModeDigital, 10130, 10150),
+	),
+	bandplan.Band20m: band(bandplan.Band20m, 14000, 14350,
+		portion(bandplan.ModeCW, 14000, 14070),
+		portion(bandplan.ModeDigital, 14070, 14099),
+		portion(bandplan.ModeBeacon, 14099, 14101),
+		portion(bandplan.ModeDigital, 14101, 14112),
+		portion(bandplan.ModePhone, 14112, 14350),
+	),
+	bandplan.Band17m: band(bandplan.Band17m, 18068, 18168,
+		portion(bandplan.ModeCW, 18068, 18095),
+		portion(bandplan.ModeDigital, 18095, 18109),
+		portion(bandplan.ModeBeacon, 18109, 18111),
+		portion(bandplan.ModePhone, 18111, 18168),
+	),
+	bandplan.Band15m: band(bandplan.Band15m, 21000, 21450,
+		portion(bandplan.ModeCW, 21000, 21070),
+		portion(bandplan.ModeDigital, 21070, 21149),
+		portion(bandplan.ModeBeacon, 21149, 21151),
+		portion(bandplan.ModePhone, 21151, 21450),
+	),
+	bandplan.Band12m: band(bandplan.Band12m, 24890, 24990,
+		portion(bandplan.ModeCW, 24890, 24915),
+		portion(bandplan.ModeDigital, 24915, 24929),
+		portion(bandplan.ModeBeacon, 24929, 24931),
+		portion(bandplan.ModePhone, 24931, 24990),
+	),
+	bandplan.Band10m: band(bandplan.Band10m, 28000, 29700,
+		portion(bandplan.ModeCW, 28000, 28070),
+		portion(bandplan.ModeDigital, 28070, 28190),
+		portion(bandplan.ModeBeacon, 28190, 28225),
+		portion(bandplan.ModePhone, 28225, 29700),
+	),
+}
+
+// band returns a band with the given limits in kHz.
+func band(name bandplan.BandName, fromKHz, toKHz float64, portions ...bandplan.Portion) bandplan.Band {
+	return bandplan.Band{
+		Name:           name,
+		FrequencyRange: hamradio.FrequencyRange{From: hamradio.Frequency(fromKHz * 1000), To: hamradio.Frequency(toKHz * 1000)},
+		Portions:       portions,
+	}
+}
+
+// portion returns a portion with the given limits in kHz.
+func portion(mode bandplan.Mode, fromKHz, toKHz float64) bandplan.Portion {
+	return bandplan.Portion{
+		Mode:           mode,
+		FrequencyRange: hamradio.FrequencyRange{From: hamradio.Frequency(fromKHz * 1000), To: hamradio.Frequency(toKHz * 1000)},
+	}
+}
+
+// Segment returns the bandplan mode in which the given hamlib mode is allowed. The result is false if the hamlib mode
+// is not covered by the bandplan.
+func Segment(hamlibMode string) (bandplan.Mode, bool) {
+	switch hamlibMode {
+	case "CW", "CWR":
+		return bandplan.ModeCW, true
+	case "USB", "LSB", "AM", "FM":
+		return bandplan.ModePhone, true
+	case "RTTY", "RTTYR", "PKTUSB", "PKTLSB", "PKTFM":
+		return bandplan.ModeDigital, true
+	default:
+		return "", false
+	}
+}
+
+// InSegment indicates if the given frequency is inside a portion of the given band that allows the given hamlib mode.
+// Frequencies outside of the band and modes that are not covered by the bandplan are always inside.
+func InSegment(band bandplan.Band, f hamradio.Frequency, hamlibMode string) bool {
+	mode, ok := Segment(hamlibMode)
+	if !ok || !band.Contains(f) {
+		return true
+	}
+	covered := false
+	for _, portion := range band.Portions {
+		if portion.Mode != mode {
+			continue
+		}
+		covered = true
+		if portion.Contains(f) {
+			return true
+		}
+	}
+	return !covered
+}
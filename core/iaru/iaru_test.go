@@ -0,0 +1,67 @@
+package iaru
+
+import (
+	"testing"
+
+	"github.com/ftl/hamradio"
+	"github.com/ftl/hamradio/bandplan"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBandplan(t *testing.T) {
+	for region := 1; region <= 3; region++ {
+		plan, err := Bandplan(region)
+		assert.NoError(t, err)
+		assert.Equal(t, bandplan.Band20m, plan.ByFrequency(14025000).Name, "region %d", region)
+	}
+	_, err := Bandplan(4)
+	assert.Error(t, err)
+
+	assert.Equal(t, bandplan.Band80m, Region2.ByFrequency(3950000).Name)
+	assert.Equal(t, bandplan.BandUnknown, bandplan.IARURegion1.ByFrequency(3950000).Name)
+}
+
+func TestSameBandsInAllRegions(t *testing.T) {
+	for name, plan := range map[string]bandplan.Bandplan{"region 2": Region2, "region 3": Region3} {
+		assert.Equal(t, len(bandplan.IARURegion1), len(plan), name)
+		for bandName := range bandplan.IARURegion1 {
+			band, ok := plan[bandName]
+			assert.True(t, ok, "%s %s", name, bandName)
+			assert.Equal(t, bandName, band.Name, name)
+		}
+	}
+	assert.Equal(t, bandplan.Band60m, Region2.ByFrequency(5357000).Name)
+	assert.Equal(t, bandplan.Band60m, Region3.ByFrequency(5357000).Name)
+}
+
+func TestPortionsInsideBands(t *testing.T) {
+	for name, plan := range map[string]bandplan.Bandplan{"region 2": Region2, "region 3": Region3} {
+		for _, band := range plan {
+			for _, portion := range band.Portions {
+				assert.True(t, band.Contains(portion.From) && band.Contains(portion.To), "%s %s %v", name, band.Name, portion)
+				assert.True(t, portion.From < portion.To, "%s %s %v", name, band.Name, portion)
+			}
+		}
+	}
+}
+
+func TestInSegment(t *testing.T) {
+	band := Region2[bandplan.Band40m]
+	tt := []struct {
+		f        hamradio.Frequency
+		mode     string
+		expected bool
+	}{
+		{7020000, "CW", true},
+		{7020000, "LSB", false},
+		{7200000, "LSB", true},
+		{7200000, "CW", false},
+		{7045000, "PKTUSB", true},
+		{7045000, "USB", false},
+		{7200000, "WFM", true},
+		{14200000, "CW", true},
+	}
+	for _, tc := range tt {
+		assert.Equal(t, tc.expected, InSegment(band, tc.f, tc.mode), "%v %s", tc.f, tc.mode)
+	}
+}
@@ -0,0 +1,89 @@
+// Package iq provides reading and writing of files with raw IQ samples.
+package iq
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ftl/panacotta/core"
+)
+
+// Format of an IQ file.
+type Format string
+
+// All supported formats.
+const (
+	// FormatWAV is a WAV file with two 16-bit PCM channels (I and Q).
+	FormatWAV Format = "wav"
+	// FormatCF32 is a raw stream of interleaved 32-bit float values (I, Q).
+	FormatCF32 Format = "cf32"
+	// FormatCU8 is a raw stream of interleaved unsigned 8-bit values (I, Q), as written by rtl_sdr.
+	FormatCU8 Format = "cu8"
+)
+
+// ParseFormat parses the given string into a format.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatWAV, FormatCF32, FormatCU8:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown IQ file format %q", s)
+	}
+}
+
+// FormatOf returns the format of the given file, derived from the file extension.
+func FormatOf(filename string) (Format, error) {
+	ext := strings.TrimPrefix(filepath.Ext(filename), ".")
+	switch strings.ToLower(ext) {
+	case "bin", "raw":
+		return FormatCU8, nil
+	case "cfile", "fc32":
+		return FormatCF32, nil
+	}
+	return ParseFormat(ext)
+}
+
+// Metadata of an IQ file, it is stored in a JSON sidecar file next to the IQ file.
+type Metadata struct {
+	Format     Format         `json:"format"`
+	SampleRate int            `json:"sampleRate"`
+	IFCenter   core.Frequency `json:"ifCenter"`
+	RXCenter   core.Frequency `json:"rxCenter"`
+	VFO        core.Frequency `json:"vfo"`
+	Start      time.Time      `json:"start"`
+}
+
+// SidecarFilename returns the name of the sidecar file that belongs to the given IQ file.
+func SidecarFilename(filename string) string {
+	return strings.TrimSuffix(filename, filepath.Ext(filename)) + ".json"
+}
+
+// WriteMetadata writes the given metadata into the sidecar file of the given IQ file.
+func WriteMetadata(filename string, metadata Metadata) error {
+	bytes, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(SidecarFilename(filename), bytes, 0644)
+}
+
+// ReadMetadata reads the metadata from the sidecar file of the given IQ file.
+func ReadMetadata(filename string) (Metadata, error) {
+	bytes, err := ioutil.ReadFile(SidecarFilename(filename))
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	var result Metadata
+	err = json.Unmarshal(bytes, &result)
+	if err != nil {
+		return Metadata{}, errors.Wrapf(err, "invalid IQ metadata in %s", SidecarFilename(filename))
+	}
+	return result, nil
+}
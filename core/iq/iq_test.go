@@ -0,0 +1,183 @@
+package iq
+
+import (
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatOf(t *testing.T) {
+	tt := []struct {
+		filename string
+		expected Format
+		invalid  bool
+	}{
+		{"rec.wav", FormatWAV, false},
+		{"rec.WAV", FormatWAV, false},
+		{"rec.cu8", FormatCU8, false},
+		{"rec.bin", FormatCU8, false},
+		{"rec.cf32", FormatCF32, false},
+		{"rec.cfile", FormatCF32, false},
+		{"rec.mp3", "", true},
+	}
+	for _, tc := range tt {
+		t.Run(tc.filename, func(t *testing.T) {
+			actual, err := FormatOf(tc.filename)
+			if tc.invalid {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestSidecarFilename(t *testing.T) {
+	assert.Equal(t, "/tmp/rec.json", SidecarFilename("/tmp/rec.wav"))
+	assert.Equal(t, "rec.json", SidecarFilename("rec"))
+}
+
+func TestWriteWAV(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "rec.wav")
+
+	w, err := Create(filename, Metadata{Format: FormatWAV, SampleRate: 48000, VFO: 7000000})
+	require.NoError(t, err)
+	require.NoError(t, w.Write([]complex128{complex(1, -1), complex(0, 0.5)}))
+	require.NoError(t, w.Close())
+
+	bytes, err := ioutil.ReadFile(filename)
+	require.NoError(t, err)
+	require.Equal(t, wavHeaderSize+8, len(bytes))
+	assert.Equal(t, "RIFF", string(bytes[0:4]))
+	assert.Equal(t, uint32(36+8), binary.LittleEndian.Uint32(bytes[4:]))
+	assert.Equal(t, uint16(2), binary.LittleEndian.Uint16(bytes[22:]))
+	assert.Equal(t, uint32(48000), binary.LittleEndian.Uint32(bytes[24:]))
+	assert.Equal(t, uint32(8), binary.LittleEndian.Uint32(bytes[40:]))
+	assert.Equal(t, int16(32767), int16(binary.LittleEndian.Uint16(bytes[44:])))
+	assert.Equal(t, int16(-32767), int16(binary.LittleEndian.Uint16(bytes[46:])))
+	assert.Equal(t, int16(0), int16(binary.LittleEndian.Uint16(bytes[48:])))
+	assert.Equal(t, int16(16384), int16(binary.LittleEndian.Uint16(bytes[50:])))
+
+	metadata, err := ReadMetadata(filename)
+	require.NoError(t, err)
+	assert.Equal(t, FormatWAV, metadata.Format)
+	assert.Equal(t, 48000, metadata.SampleRate)
+}
+
+func TestWriteCU8(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "rec.cu8")
+
+	r, err := NewRecorder(filename, Metadata{Format: FormatCU8, SampleRate: 1800000})
+	require.NoError(t, err)
+	r.Write([]complex128{complex(1, -1), complex(0, 2)})
+	require.NoError(t, r.Close())
+
+	bytes, err := ioutil.ReadFile(filename)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{255, 0, 128, 255}, bytes)
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "panacotta-iq")
+	require.NoError(t, err)
+	return dir
+}
+
+func TestRoundtrip(t *testing.T) {
+	samples := []complex128{complex(0.5, -0.5), complex(0, 1), complex(-1, 0.25)}
+	tt := []struct {
+		format    Format
+		tolerance float64
+	}{
+		{FormatWAV, 1.0 / 32767},
+		{FormatCF32, 1.0e-7},
+		{FormatCU8, 1.0 / 255},
+	}
+	for _, tc := range tt {
+		t.Run(string(tc.format), func(t *testing.T) {
+			dir := tempDir(t)
+			defer os.RemoveAll(dir)
+			filename := filepath.Join(dir, "rec."+string(tc.format))
+
+			w, err := Create(filename, Metadata{Format: tc.format, SampleRate: 240000})
+			require.NoError(t, err)
+			require.NoError(t, w.Write(samples))
+			require.NoError(t, w.Close())
+
+			r, err := Open(filename, 0)
+			require.NoError(t, err)
+			defer r.Close()
+			assert.Equal(t, 240000, r.SampleRate())
+			assert.Equal(t, int64(len(samples)), r.Length())
+
+			block := make([]complex128, 2)
+			n, err := r.Read(block)
+			assert.NoError(t, err)
+			assert.Equal(t, 2, n)
+			assertSamples(t, samples[0:2], block, tc.tolerance)
+
+			n, err = r.Read(block)
+			assert.Equal(t, io.EOF, err)
+			assert.Equal(t, 1, n)
+			assertSamples(t, samples[2:], block[:1], tc.tolerance)
+
+			require.NoError(t, r.SetPosition(1))
+			n, err = r.Read(block)
+			assert.NoError(t, err)
+			assert.Equal(t, 2, n)
+			assertSamples(t, samples[1:], block, tc.tolerance)
+		})
+	}
+}
+
+func TestOpenRawWithoutSidecar(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "dump.bin")
+	require.NoError(t, ioutil.WriteFile(filename, []byte{127, 127, 254, 0}, 0644))
+
+	r, err := Open(filename, 1800000)
+	require.NoError(t, err)
+	defer r.Close()
+
+	assert.Equal(t, FormatCU8, r.Format())
+	assert.Equal(t, 1800000, r.SampleRate())
+	assert.Equal(t, int64(2), r.Length())
+}
+
+func TestReadCU8IFirst(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "rtl_sdr.cu8")
+	require.NoError(t, ioutil.WriteFile(filename, []byte{255, 0, 0, 255, 255, 255, 128, 0}, 0644))
+
+	r, err := Open(filename, 2400000)
+	require.NoError(t, err)
+	defer r.Close()
+
+	block := make([]complex128, 4)
+	n, err := r.Read(block)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, n)
+	assertSamples(t, []complex128{complex(1, -1), complex(-1, 1), complex(1, 1), complex(0, -1)}, block, 1.0/255)
+}
+
+func assertSamples(t *testing.T, expected, actual []complex128, tolerance float64) {
+	t.Helper()
+	require.Equal(t, len(expected), len(actual))
+	for i := range expected {
+		assert.InDelta(t, real(expected[i]), real(actual[i]), tolerance, "real part of sample %d", i)
+		assert.InDelta(t, imag(expected[i]), imag(actual[i]), tolerance, "imaginary part of sample %d", i)
+	}
+}
@@ -0,0 +1,282 @@
+package iq
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	wavFormatPCM        = 1
+	wavFormatFloat      = 3
+	wavFormatExtensible = 0xFFFE
+)
+
+// Open an IQ file for reading. The format is derived from the file extension. WAV files contain the sample rate in their
+// header, for raw files the sample rate is taken from the sidecar file. If there is no sidecar file, the given default
+// sample rate is used.
+func Open(filename string, defaultSampleRate int) (*Reader, error) {
+	format, err := FormatOf(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot open IQ file")
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	result := &Reader{
+		file:       file,
+		format:     format,
+		sampleRate: defaultSampleRate,
+		dataSize:   stat.Size(),
+	}
+	metadata, err := ReadMetadata(filename)
+	if err == nil && metadata.SampleRate > 0 {
+		result.sampleRate = metadata.SampleRate
+	}
+
+	switch format {
+	case FormatCU8:
+		result.sampleFormat = sampleUint8
+	case FormatCF32:
+		result.sampleFormat = sampleFloat32
+	case FormatWAV:
+		err = result.readWAVHeader(stat.Size())
+		if err != nil {
+			file.Close()
+			return nil, errors.Wrapf(err, "invalid WAV file %s", filename)
+		}
+	}
+	if result.sampleRate <= 0 {
+		file.Close()
+		return nil, errors.Errorf("unknown sample rate of %s", filename)
+	}
+
+	_, err = file.Seek(result.dataOffset, io.SeekStart)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return result, nil
+}
+
+type sampleFormat int
+
+const (
+	sampleUint8 sampleFormat = iota
+	sampleInt16
+	sampleFloat32
+)
+
+func (f sampleFormat) size() int {
+	switch f {
+	case sampleUint8:
+		return 2
+	case sampleInt16:
+		return 4
+	case sampleFloat32:
+		return 8
+	default:
+		return 0
+	}
+}
+
+// Reader reads IQ samples from a file.
+type Reader struct {
+	file         *os.File
+	format       Format
+	sampleFormat sampleFormat
+	sampleRate   int
+	dataOffset   int64
+	dataSize     int64
+	position     int64
+	buffer       []byte
+}
+
+func (r *Reader) readWAVHeader(fileSize int64) error {
+	header := make([]byte, 12)
+	_, err := io.ReadFull(r.file, header)
+	if err != nil {
+		return err
+	}
+	if string(header[0:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return errors.New("no RIFF/WAVE header")
+	}
+
+	offset := int64(len(header))
+	formatFound := false
+	chunkHeader := make([]byte, 8)
+	for {
+		_, err = io.ReadFull(r.file, chunkHeader)
+		if err != nil {
+			return errors.Wrap(err, "no data chunk")
+		}
+		offset += int64(len(chunkHeader))
+		chunkID := string(chunkHeader[0:4])
+		chunkSize := int64(binary.LittleEndian.Uint32(chunkHeader[4:]))
+
+		switch chunkID {
+		case "fmt ":
+			chunk := make([]byte, chunkSize)
+			_, err = io.ReadFull(r.file, chunk)
+			if err != nil {
+				return err
+			}
+			err = r.parseWAVFormat(chunk)
+			if err != nil {
+				return err
+			}
+			formatFound = true
+		case "data":
+			if !formatFound {
+				return errors.New("data chunk before fmt chunk")
+			}
+			r.dataOffset = offset
+			r.dataSize = fileSize - offset
+			if chunkSize < r.dataSize && chunkSize != math.MaxUint32 {
+				r.dataSize = chunkSize
+			}
+			return nil
+		default:
+			_, err = r.file.Seek(chunkSize, io.SeekCurrent)
+			if err != nil {
+				return err
+			}
+		}
+		offset += chunkSize
+		if chunkSize%2 == 1 {
+			_, err = r.file.Seek(1, io.SeekCurrent)
+			if err != nil {
+				return err
+			}
+			offset++
+		}
+	}
+}
+
+func (r *Reader) parseWAVFormat(chunk []byte) error {
+	if len(chunk) < 16 {
+		return errors.New("fmt chunk too short")
+	}
+	formatTag := binary.LittleEndian.Uint16(chunk[0:])
+	channels := binary.LittleEndian.Uint16(chunk[2:])
+	sampleRate := binary.LittleEndian.Uint32(chunk[4:])
+	bitsPerSample := binary.LittleEndian.Uint16(chunk[14:])
+	if formatTag == wavFormatExtensible && len(chunk) >= 26 {
+		formatTag = binary.LittleEndian.Uint16(chunk[24:])
+	}
+
+	if channels != 2 {
+		return errors.Errorf("%d channels, IQ files need 2 channels", channels)
+	}
+	switch {
+	case formatTag == wavFormatPCM && bitsPerSample == 8:
+		r.sampleFormat = sampleUint8
+	case formatTag == wavFormatPCM && bitsPerSample == 16:
+		r.sampleFormat = sampleInt16
+	case formatTag == wavFormatFloat && bitsPerSample == 32:
+		r.sampleFormat = sampleFloat32
+	default:
+		return errors.Errorf("unsupported sample format %d with %d bits", formatTag, bitsPerSample)
+	}
+	r.sampleRate = int(sampleRate)
+	return nil
+}
+
+// Format of the file.
+func (r *Reader) Format() Format {
+	return r.format
+}
+
+// SampleRate of the file in samples per second.
+func (r *Reader) SampleRate() int {
+	return r.sampleRate
+}
+
+// Length of the file in samples.
+func (r *Reader) Length() int64 {
+	return r.dataSize / int64(r.sampleFormat.size())
+}
+
+// Position of the next sample that is read.
+func (r *Reader) Position() int64 {
+	return r.position
+}
+
+// SetPosition moves to the given sample position.
+func (r *Reader) SetPosition(position int64) error {
+	if position < 0 || position > r.Length() {
+		return errors.Errorf("position %d out of range [0, %d]", position, r.Length())
+	}
+	_, err := r.file.Seek(r.dataOffset+position*int64(r.sampleFormat.size()), io.SeekStart)
+	if err != nil {
+		return err
+	}
+	r.position = position
+	return nil
+}
+
+// Read the next samples into the given block. Read returns the number of samples that were read. If the end of the
+// file is reached before the block is filled, Read returns io.EOF.
+func (r *Reader) Read(block []complex128) (int, error) {
+	remaining := r.Length() - r.position
+	if remaining <= 0 {
+		return 0, io.EOF
+	}
+	count := len(block)
+	if int64(count) > remaining {
+		count = int(remaining)
+	}
+
+	sampleSize := r.sampleFormat.size()
+	if len(r.buffer) < count*sampleSize {
+		r.buffer = make([]byte, count*sampleSize)
+	}
+	n, err := io.ReadFull(r.file, r.buffer[:count*sampleSize])
+	count = n / sampleSize
+	for i := 0; i < count; i++ {
+		block[i] = r.decode(r.buffer[i*sampleSize:])
+	}
+	r.position += int64(count)
+	if err == io.ErrUnexpectedEOF || (err == nil && count < len(block)) {
+		err = io.EOF
+	}
+	return count, err
+}
+
+func (r *Reader) decode(b []byte) complex128 {
+	switch r.sampleFormat {
+	case sampleUint8:
+		return decodeUint8(b) // I first, like rtl_sdr writes them
+	case sampleInt16:
+		return complex(fromInt16(b[0:]), fromInt16(b[2:]))
+	case sampleFloat32:
+		return complex(fromFloat32(b[0:]), fromFloat32(b[4:]))
+	default:
+		return 0
+	}
+}
+
+func fromInt16(b []byte) float64 {
+	return float64(int16(binary.LittleEndian.Uint16(b))) / float64(math.MaxInt16)
+}
+
+func fromFloat32(b []byte) float64 {
+	return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+}
+
+// Close the file.
+func (r *Reader) Close() error {
+	return r.file.Close()
+}
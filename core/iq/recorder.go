@@ -0,0 +1,91 @@
+package iq
+
+import (
+	"log"
+)
+
+const recorderBufferSize = 8
+
+// NewRecorder returns a new recorder that writes into a new IQ file with the given name. The samples are written
+// asynchronously, so that the recorder can be fed from the main loop without blocking it.
+func NewRecorder(filename string, metadata Metadata) (*Recorder, error) {
+	writer, err := Create(filename, metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Recorder{
+		filename: filename,
+		writer:   writer,
+		blocks:   make(chan []complex128, recorderBufferSize),
+		free:     make(chan []complex128, recorderBufferSize),
+		done:     make(chan error, 1),
+	}
+
+	go result.run()
+
+	return result, nil
+}
+
+// Recorder writes blocks of IQ samples asynchronously into a file.
+type Recorder struct {
+	filename string
+	writer   *Writer
+	blocks   chan []complex128
+	free     chan []complex128
+	done     chan error
+}
+
+func (r *Recorder) run() {
+	defer log.Printf("IQ recording %s finished", r.filename)
+	var err error
+	for block := range r.blocks {
+		if err != nil {
+			continue
+		}
+		err = r.writer.Write(block)
+		if err != nil {
+			log.Printf("IQ recording %s failed: %v", r.filename, err)
+		}
+		select {
+		case r.free <- block:
+		default:
+		}
+	}
+	closeErr := r.writer.Close()
+	if err == nil {
+		err = closeErr
+	}
+	r.done <- err
+}
+
+// Filename of the recording.
+func (r *Recorder) Filename() string {
+	return r.filename
+}
+
+// Write the given block of samples. The samples are copied, the caller may reuse the given block afterwards.
+func (r *Recorder) Write(block []complex128) {
+	var buffer []complex128
+	select {
+	case buffer = <-r.free:
+	default:
+	}
+	if cap(buffer) < len(block) {
+		buffer = make([]complex128, len(block))
+	}
+	buffer = buffer[:len(block)]
+	copy(buffer, block)
+
+	select {
+	case r.blocks <- buffer:
+	default:
+		log.Print("IQ recording buffer overflow, dropping samples")
+	}
+}
+
+// Close the recording and wait until all pending samples are written.
+func (r *Recorder) Close() error {
+	close(r.blocks)
+	return <-r.done
+}
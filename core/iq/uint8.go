@@ -0,0 +1,44 @@
+package iq
+
+import "math"
+
+// uint8Samples maps every unsigned 8-bit sample value to its normalized value. The zero level of the unsigned samples
+// lies between 127 and 128, so 0 and 255 are mapped to -1 and 1.
+var uint8Samples [256]float64
+
+func init() {
+	for i := range uint8Samples {
+		uint8Samples[i] = fromUint8(byte(i))
+	}
+}
+
+// DecodeUint8 converts a block of interleaved unsigned 8-bit samples, as delivered by RTL-SDR dongles, into the given
+// block of complex samples, which must hold at least half as many samples as the input block. The first byte of each
+// pair is I, the second is Q, like librtlsdr delivers them. It returns the converted part of the given block.
+func DecodeUint8(samples []complex128, block []byte) []complex128 {
+	result := samples[:len(block)/2]
+	for i := range result {
+		result[i] = decodeUint8(block[2*i:])
+	}
+	return result
+}
+
+func decodeUint8(b []byte) complex128 {
+	return complex(uint8Samples[b[0]], uint8Samples[b[1]])
+}
+
+// encodeUint8 is the inverse of decodeUint8.
+func encodeUint8(b []byte, s complex128) {
+	b[0] = toUint8(real(s))
+	b[1] = toUint8(imag(s))
+}
+
+const uint8Center = float64(math.MaxUint8) / 2
+
+func fromUint8(b byte) float64 {
+	return (float64(b) - uint8Center) / uint8Center
+}
+
+func toUint8(v float64) byte {
+	return byte(math.Max(0, math.Min(math.MaxUint8, math.Round(v*uint8Center+uint8Center))))
+}
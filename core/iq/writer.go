@@ -0,0 +1,151 @@
+package iq
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	wavHeaderSize    = 44
+	wavChannels      = 2
+	wavBitsPerSample = 16
+)
+
+// Create a new IQ file with the given name. The format is taken from the given metadata, which is
+// also written into the sidecar file.
+func Create(filename string, metadata Metadata) (*Writer, error) {
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create IQ file")
+	}
+
+	result := &Writer{
+		file:       file,
+		out:        bufio.NewWriterSize(file, 1<<20),
+		format:     metadata.Format,
+		sampleRate: metadata.SampleRate,
+	}
+
+	if result.format == FormatWAV {
+		err = writeWAVHeader(result.out, result.sampleRate, 0)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+
+	err = WriteMetadata(filename, metadata)
+	if err != nil {
+		file.Close()
+		return nil, errors.Wrap(err, "cannot write IQ metadata")
+	}
+
+	return result, nil
+}
+
+// Writer writes blocks of IQ samples into a file.
+type Writer struct {
+	file       *os.File
+	out        *bufio.Writer
+	format     Format
+	sampleRate int
+	dataSize   int64
+	buffer     []byte
+}
+
+// Write the given block of samples.
+func (w *Writer) Write(block []complex128) error {
+	var bytesPerSample int
+	switch w.format {
+	case FormatWAV:
+		bytesPerSample = 4
+	case FormatCF32:
+		bytesPerSample = 8
+	case FormatCU8:
+		bytesPerSample = 2
+	default:
+		return errors.Errorf("cannot write IQ format %q", w.format)
+	}
+	if len(w.buffer) != len(block)*bytesPerSample {
+		w.buffer = make([]byte, len(block)*bytesPerSample)
+	}
+
+	for i, s := range block {
+		j := i * bytesPerSample
+		switch w.format {
+		case FormatWAV:
+			binary.LittleEndian.PutUint16(w.buffer[j:], uint16(toInt16(real(s))))
+			binary.LittleEndian.PutUint16(w.buffer[j+2:], uint16(toInt16(imag(s))))
+		case FormatCF32:
+			binary.LittleEndian.PutUint32(w.buffer[j:], math.Float32bits(float32(real(s))))
+			binary.LittleEndian.PutUint32(w.buffer[j+4:], math.Float32bits(float32(imag(s))))
+		case FormatCU8:
+			encodeUint8(w.buffer[j:], s)
+		}
+	}
+
+	n, err := w.out.Write(w.buffer)
+	w.dataSize += int64(n)
+	return err
+}
+
+// Close the file. For WAV files, the header is updated with the actual size of the data.
+func (w *Writer) Close() error {
+	err := w.out.Flush()
+	if err != nil {
+		w.file.Close()
+		return err
+	}
+
+	if w.format == FormatWAV {
+		_, err = w.file.Seek(0, io.SeekStart)
+		if err == nil {
+			err = writeWAVHeader(w.file, w.sampleRate, w.dataSize)
+		}
+		if err != nil {
+			w.file.Close()
+			return errors.Wrap(err, "cannot update WAV header")
+		}
+	}
+
+	return w.file.Close()
+}
+
+func toInt16(v float64) int16 {
+	return int16(math.Max(math.MinInt16, math.Min(math.MaxInt16, math.Round(v*math.MaxInt16))))
+}
+
+// writeWAVHeader writes a canonical 44 byte RIFF/WAVE header. WAV files are limited to 4GiB, if the
+// data is larger, the size fields are saturated and readers need to read until the end of the file.
+func writeWAVHeader(out io.Writer, sampleRate int, dataSize int64) error {
+	blockAlign := wavChannels * wavBitsPerSample / 8
+	header := make([]byte, wavHeaderSize)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], saturatedUint32(dataSize+wavHeaderSize-8))
+	copy(header[8:], "WAVE")
+	copy(header[12:], "fmt ")
+	binary.LittleEndian.PutUint32(header[16:], 16)
+	binary.LittleEndian.PutUint16(header[20:], 1) // PCM
+	binary.LittleEndian.PutUint16(header[22:], wavChannels)
+	binary.LittleEndian.PutUint32(header[24:], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(header[28:], uint32(sampleRate*blockAlign))
+	binary.LittleEndian.PutUint16(header[32:], uint16(blockAlign))
+	binary.LittleEndian.PutUint16(header[34:], wavBitsPerSample)
+	copy(header[36:], "data")
+	binary.LittleEndian.PutUint32(header[40:], saturatedUint32(dataSize))
+
+	_, err := out.Write(header)
+	return err
+}
+
+func saturatedUint32(v int64) uint32 {
+	if v > math.MaxUint32 {
+		return math.MaxUint32
+	}
+	return uint32(v)
+}
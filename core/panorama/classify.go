@@ -0,0 +1,113 @@
+package panorama
+
+import (
+	"math"
+	"time"
+
+	"github.com/ftl/panacotta/core"
+)
+
+const (
+	cwMaxWidth      = core.Frequency(150)
+	digitalMaxWidth = core.Frequency(100)
+	ssbMinWidth     = core.Frequency(1800)
+	ssbMaxWidth     = core.Frequency(3200)
+
+	rttyShift          = core.Frequency(170)
+	rttyShiftTolerance = core.Frequency(40)
+	rttyMinDip         = 6.0 // dB between the two tones
+
+	ftSlotLength     = 7500 * time.Millisecond // FT4 slots, every FT8 slot starts with an FT4 slot
+	ftOnsetTolerance = 2 * time.Second
+	ftMinSlotSignals = 2
+	lsbBelow         = core.Frequency(10000000)
+	ssbTuneOffset    = core.Frequency(1500)
+)
+
+// classify the given peak by its bandwidth, its spectral shape and the time it was detected first. The slot activity
+// contains the number of narrow signals that started in each FT8/FT4 slot.
+func classify(peak peak, slotActivity map[time.Time]int) core.SignalClass {
+	width := peak.frequencyRange.Width()
+	switch {
+	case peak.twoTone:
+		return core.SignalRTTY
+	case width >= ssbMinWidth && width <= ssbMaxWidth:
+		return core.SignalSSB
+	case width <= digitalMaxWidth && slotAligned(peak.firstSeen) && slotActivity[slotStart(peak.firstSeen)] >= ftMinSlotSignals:
+		return core.SignalFT8
+	case width <= cwMaxWidth:
+		return core.SignalCW
+	default:
+		return core.SignalUnknown
+	}
+}
+
+// slotActivity counts the narrow signals that started at the beginning of each FT8/FT4 slot.
+func slotActivity(peaks map[peakKey]peak) map[time.Time]int {
+	result := make(map[time.Time]int)
+	for _, peak := range peaks {
+		if peak.frequencyRange.Width() > digitalMaxWidth || !slotAligned(peak.firstSeen) {
+			continue
+		}
+		result[slotStart(peak.firstSeen)]++
+	}
+	return result
+}
+
+func slotStart(t time.Time) time.Time {
+	return t.UTC().Truncate(ftSlotLength)
+}
+
+func slotAligned(t time.Time) bool {
+	return t.Sub(slotStart(t)) < ftOnsetTolerance
+}
+
+// tuneFrequency returns the VFO frequency that is appropriate to receive the signal of the given peak.
+func tuneFrequency(peak peak, class core.SignalClass) core.Frequency {
+	switch class {
+	case core.SignalSSB:
+		if peak.maxFrequency < lsbBelow {
+			return peak.frequencyRange.From + ssbTuneOffset
+		}
+		return peak.frequencyRange.To - ssbTuneOffset
+	case core.SignalRTTY:
+		return peak.frequencyRange.Center()
+	default:
+		return peak.maxFrequency
+	}
+}
+
+// twoTone checks if the given part of the spectrum contains the mark and space tones of an RTTY signal.
+func twoTone(fft core.FFT, indexRange core.PeakIndexRange) bool {
+	from := int(math.Max(float64(indexRange.From), 1))
+	to := int(math.Min(float64(indexRange.To), float64(len(fft.Data)-2)))
+	resolution := fft.Resolution()
+	if resolution <= 0 {
+		return false
+	}
+
+	maxima := make([]int, 0, to-from+1)
+	for i := from; i <= to; i++ {
+		if fft.Data[i] > fft.Data[i-1] && fft.Data[i] >= fft.Data[i+1] {
+			maxima = append(maxima, i)
+		}
+	}
+
+	for i, mark := range maxima {
+		for _, space := range maxima[i+1:] {
+			shift := core.Frequency(float64(space-mark) * resolution)
+			if math.Abs(float64(shift-rttyShift)) > float64(rttyShiftTolerance) {
+				continue
+			}
+			tones := math.Min(fft.Data[mark], fft.Data[space])
+			dip := tones
+			for j := mark + 1; j < space; j++ {
+				dip = math.Min(dip, fft.Data[j])
+			}
+			if tones-dip >= rttyMinDip {
+				return true
+			}
+		}
+	}
+	return false
+}
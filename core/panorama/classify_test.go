@@ -0,0 +1,85 @@
+package panorama
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ftl/panacotta/core"
+)
+
+func TestClassify(t *testing.T) {
+	slot := time.Date(2020, 6, 1, 12, 0, 15, 0, time.UTC)
+	activity := map[time.Time]int{slot: 3}
+	tt := []struct {
+		name      string
+		width     core.Frequency
+		twoTone   bool
+		firstSeen time.Time
+		expected  core.SignalClass
+	}{
+		{"cw", 80, false, slot.Add(5 * time.Second), core.SignalCW},
+		{"ssb", 2400, false, slot, core.SignalSSB},
+		{"ft8", 50, false, slot.Add(500 * time.Millisecond), core.SignalFT8},
+		{"narrow, but not at the start of a slot", 50, false, slot.Add(4 * time.Second), core.SignalCW},
+		{"narrow, but alone in its slot", 50, false, slot.Add(7500 * time.Millisecond), core.SignalCW},
+		{"rtty", 300, true, slot, core.SignalRTTY},
+		{"unknown", 800, false, slot, core.SignalUnknown},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			p := peak{
+				frequencyRange: core.FrequencyRange{From: 7000000, To: 7000000 + tc.width},
+				twoTone:        tc.twoTone,
+				firstSeen:      tc.firstSeen,
+			}
+			assert.Equal(t, tc.expected, classify(p, activity))
+		})
+	}
+}
+
+func TestSlotActivity(t *testing.T) {
+	slot := time.Date(2020, 6, 1, 12, 0, 30, 0, time.UTC)
+	peaks := map[peakKey]peak{
+		1: {frequencyRange: core.FrequencyRange{From: 7074500, To: 7074550}, firstSeen: slot.Add(600 * time.Millisecond)},
+		2: {frequencyRange: core.FrequencyRange{From: 7075000, To: 7075050}, firstSeen: slot.Add(800 * time.Millisecond)},
+		3: {frequencyRange: core.FrequencyRange{From: 7076000, To: 7076050}, firstSeen: slot.Add(5 * time.Second)},
+		4: {frequencyRange: core.FrequencyRange{From: 7077000, To: 7079400}, firstSeen: slot.Add(600 * time.Millisecond)},
+	}
+
+	assert.Equal(t, map[time.Time]int{slot: 2}, slotActivity(peaks))
+}
+
+func TestTuneFrequency(t *testing.T) {
+	lsb := peak{frequencyRange: core.FrequencyRange{From: 7150000, To: 7152400}, maxFrequency: 7151000}
+	usb := peak{frequencyRange: core.FrequencyRange{From: 14250000, To: 14252400}, maxFrequency: 14251000}
+	rtty := peak{frequencyRange: core.FrequencyRange{From: 14080000, To: 14080300}, maxFrequency: 14080050}
+
+	assert.Equal(t, core.Frequency(7151500), tuneFrequency(lsb, core.SignalSSB))
+	assert.Equal(t, core.Frequency(14250900), tuneFrequency(usb, core.SignalSSB))
+	assert.Equal(t, core.Frequency(14080150), tuneFrequency(rtty, core.SignalRTTY))
+	assert.Equal(t, core.Frequency(7151000), tuneFrequency(lsb, core.SignalCW))
+}
+
+func TestTwoTone(t *testing.T) {
+	data := make([]float64, 40)
+	for i := range data {
+		data[i] = -100
+	}
+	fft := core.FFT{Data: data, Range: core.FrequencyRange{From: 14080000, To: 14080400}} // 10 Hz per bin
+	peakRange := core.PeakIndexRange{From: 5, To: 30}
+
+	assert.False(t, twoTone(fft, peakRange), "noise")
+
+	data[10] = -60
+	assert.False(t, twoTone(fft, peakRange), "single tone")
+
+	data[27] = -62
+	assert.True(t, twoTone(fft, peakRange), "170 Hz shift")
+
+	for i := 11; i < 27; i++ {
+		data[i] = -64
+	}
+	assert.False(t, twoTone(fft, peakRange), "no dip between the tones")
+}
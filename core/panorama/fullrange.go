@@ -28,6 +28,7 @@ func (p Panorama) fullRangeData() core.Panorama {
 		Spectrum:           p.fullRangeSpectrum(),
 		PeakThresholdLevel: 0.0,
 		Peaks:              []core.PeakMark{},
+		Overload:           p.overload(),
 	}
 
 	return result
@@ -3,10 +3,12 @@ package panorama
 import (
 	"log"
 	"math"
+	"sort"
 	"time"
 
 	"github.com/ftl/hamradio/bandplan"
 	"github.com/ftl/panacotta/core"
+	"github.com/ftl/panacotta/core/iaru"
 )
 
 // Panorama controller
@@ -17,6 +19,9 @@ type Panorama struct {
 	dbRange        core.DBRange
 	vfo            core.VFO
 	band           bandplan.Band
+	plan           bandplan.Bandplan
+	segmentWarning bool
+	freezeOnTX     bool
 
 	resolution            map[core.ViewMode]core.HzPerPx
 	viewMode              core.ViewMode
@@ -25,20 +30,56 @@ type Panorama struct {
 	signalDetectionActive bool
 
 	fft             core.FFT
+	inputState      core.InputState
 	peakBuffer      map[peakKey]peak
 	peakTimeout     time.Duration
+	cwTexts         map[peakKey]cwText
+	spots           map[string]core.Spot
+	skimmerSpots    map[string][]skimmerSpot // by callsign
+	bookmarks       []core.Bookmark
+	spotTimeout     time.Duration
+	activityLog     activityLog
 	dbRangeAdjusted bool
 }
 
+type activityLog interface {
+	Log(core.SignalActivity)
+}
+
 type peak struct {
 	frequencyRange core.FrequencyRange
 	maxFrequency   core.Frequency
 	valueDB        core.DB
+	maxValueDB     core.DB
+	snr            core.DB
+	twoTone        bool
+	class          core.SignalClass
+	firstSeen      time.Time
 	lastSeen       time.Time
 }
 
 type peakKey uint
 
+type cwText struct {
+	core.CWText
+	received time.Time
+}
+
+type skimmerSpot struct {
+	frequency core.Frequency
+	count     int // the number of aggregated spots, frequency is their mean
+	snr       core.DB
+	wpm       int
+	spotters  map[string]bool
+	lastSeen  time.Time
+}
+
+// cwTextTolerance is the maximum distance between a peak and the frequency of a CW text that belongs to this peak.
+const cwTextTolerance = core.Frequency(50)
+
+// skimmerSpotTolerance is the maximum distance between skimmer spots of the same callsign that are aggregated.
+const skimmerSpotTolerance = core.Frequency(300)
+
 func toPeakKey(f core.Frequency) peakKey {
 	return peakKey(f / 100.0)
 }
@@ -46,6 +87,7 @@ func toPeakKey(f core.Frequency) peakKey {
 const (
 	defaultFixedResolution    = core.HzPerPx(100)
 	defaultCenteredResolution = core.HzPerPx(25)
+	overloadThreshold         = 0.001
 )
 
 // New returns a new instance of panorama.
@@ -61,8 +103,13 @@ func New(width core.Px, frequencyRange core.FrequencyRange, vfoFrequency core.Fr
 		viewMode:              core.ViewFixed,
 		signalDetectionActive: true,
 		margin:                0.02,
+		plan:                  bandplan.IARURegion1,
 		peakBuffer:            make(map[peakKey]peak),
-		peakTimeout:           10 * time.Second, // TODO make this configurable
+		peakTimeout:           10 * time.Second,
+		cwTexts:               make(map[peakKey]cwText),
+		spots:                 make(map[string]core.Spot),
+		skimmerSpots:          make(map[string][]skimmerSpot),
+		spotTimeout:           10 * time.Minute,
 		dbRangeAdjusted:       true,
 	}
 
@@ -160,7 +207,7 @@ func (p *Panorama) SetVFO(vfo core.VFO) {
 	p.vfo = vfo
 
 	if !p.band.Contains(vfo.Frequency) {
-		band := bandplan.IARURegion1.ByFrequency(vfo.Frequency)
+		band := p.plan.ByFrequency(vfo.Frequency)
 		if band.Width() > 0 {
 			if p.band.Width() > 0 {
 				p.dbRangeAdjusted = false
@@ -190,10 +237,95 @@ func (p Panorama) VFO() (vfo core.VFO, band bandplan.Band) {
 	return p.vfo, p.band
 }
 
+// SetPeakPersistence sets how long a peak is shown after it was detected for the last time.
+func (p *Panorama) SetPeakPersistence(persistence time.Duration) {
+	p.peakTimeout = persistence
+}
+
+// SetActivityLog sets the log that records the detected signals when they disappear.
+func (p *Panorama) SetActivityLog(logger activityLog) {
+	p.activityLog = logger
+}
+
+// SetCWText sets the text that was decoded from the CW signal on the given frequency.
+func (p *Panorama) SetCWText(text core.CWText) {
+	p.cwTexts[toPeakKey(text.Frequency)] = cwText{CWText: text, received: time.Now()}
+}
+
+// SetSpot adds the given spot to the panorama. A new spot of the same callsign replaces the previous one.
+func (p *Panorama) SetSpot(spot core.Spot) {
+	p.spots[spot.Call] = spot
+}
+
+// SetSkimmerSpot adds the given skimmer spot to the panorama. The spots of the same callsign within the
+// skimmerSpotTolerance are aggregated.
+func (p *Panorama) SetSkimmerSpot(spot core.Spot) {
+	spots := p.skimmerSpots[spot.Call]
+	index := -1
+	for i, s := range spots {
+		if math.Abs(float64(s.frequency-spot.Frequency)) <= float64(skimmerSpotTolerance) {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		spots = append(spots, skimmerSpot{snr: spot.SNR, spotters: make(map[string]bool)})
+		index = len(spots) - 1
+	}
+	aggregated := spots[index]
+	aggregated.frequency += (spot.Frequency - aggregated.frequency) / core.Frequency(aggregated.count+1)
+	aggregated.count++
+	aggregated.snr = core.DB(math.Max(float64(aggregated.snr), float64(spot.SNR)))
+	if spot.WPM != 0 {
+		aggregated.wpm = spot.WPM
+	}
+	aggregated.spotters[spot.Spotter] = true
+	if spot.Time.After(aggregated.lastSeen) {
+		aggregated.lastSeen = spot.Time
+	}
+	spots[index] = aggregated
+	p.skimmerSpots[spot.Call] = spots
+}
+
+// SetBandplan sets the bandplan that is used to find the band and the mode segments.
+func (p *Panorama) SetBandplan(plan bandplan.Bandplan) {
+	p.plan = plan
+	p.band = bandplan.UnknownBand
+}
+
+// SetSegmentWarning enables the warning if the VFO is outside of the bandplan segment for its mode.
+func (p *Panorama) SetSegmentWarning(enabled bool) {
+	p.segmentWarning = enabled
+}
+
+// SetBookmarks sets the bookmarks that are shown in the panorama.
+func (p *Panorama) SetBookmarks(bookmarks []core.Bookmark) {
+	p.bookmarks = bookmarks
+}
+
+// SetSpotMaxAge sets how long a spot is shown after it was received.
+func (p *Panorama) SetSpotMaxAge(maxAge time.Duration) {
+	p.spotTimeout = maxAge
+}
+
 // SetFFT data
 func (p *Panorama) SetFFT(fft core.FFT) {
+	if p.frozen() {
+		return
+	}
 	p.fft = fft
 	p.adjustDBRange()
+	p.updatePeaks(time.Now())
+}
+
+// SetFreezeOnTX enables to freeze the spectrum and the detected peaks while transmitting.
+func (p *Panorama) SetFreezeOnTX(enabled bool) {
+	p.freezeOnTX = enabled
+}
+
+// frozen indicates if the spectrum is currently frozen, because the transceiver is transmitting.
+func (p Panorama) frozen() bool {
+	return p.freezeOnTX && p.vfo.PTT
 }
 
 // ToggleSignalDetection switches the signal detection on and off.
@@ -288,12 +420,24 @@ func (p *Panorama) ShiftFrequencyRange(ratio core.Frct) {
 	}
 }
 
+// SetInputState sets the current state of the samples input.
+func (p *Panorama) SetInputState(state core.InputState) {
+	p.inputState = state
+}
+
 // Data to draw the current panorama.
 func (p Panorama) Data() core.Panorama {
+	var result core.Panorama
 	if p.fullRangeMode {
-		return p.fullRangeData()
+		result = p.fullRangeData()
+	} else {
+		result = p.data()
 	}
-	return p.data()
+	result.InputState = p.inputState
+	result.Frozen = p.frozen()
+	result.FFTWindow = p.fft.Window
+	result.Averaging = p.fft.Averaging
+	return result
 }
 
 func (p Panorama) dataValid() bool {
@@ -312,25 +456,40 @@ func (p Panorama) data() core.Panorama {
 		Band:           p.band,
 		Resolution:     p.resolution[p.viewMode],
 
-		VFOLine:        core.ToFrequencyFrct(p.vfo.Frequency, p.frequencyRange),
-		VFOFilterFrom:  core.ToFrequencyFrct(p.vfo.Frequency-p.vfo.FilterWidth/2, p.frequencyRange),
-		VFOFilterTo:    core.ToFrequencyFrct(p.vfo.Frequency+p.vfo.FilterWidth/2, p.frequencyRange),
-		VFOSignalLevel: p.signalLevel(),
+		VFOLine:         core.ToFrequencyFrct(p.vfo.Frequency, p.frequencyRange),
+		VFOFilterFrom:   core.ToFrequencyFrct(p.vfo.Frequency-p.vfo.FilterWidth/2, p.frequencyRange),
+		VFOFilterTo:     core.ToFrequencyFrct(p.vfo.Frequency+p.vfo.FilterWidth/2, p.frequencyRange),
+		VFOSignalLevel:  p.signalLevel(),
+		VFOOutOfSegment: p.segmentWarning && !iaru.InSegment(p.band, p.vfo.Frequency, p.vfo.Mode),
+
+		TXVFOLine:       core.ToFrequencyFrct(p.vfo.TX.Frequency, p.frequencyRange),
+		TXVFOFilterFrom: core.ToFrequencyFrct(p.vfo.TX.Frequency-p.vfo.TX.FilterWidth/2, p.frequencyRange),
+		TXVFOFilterTo:   core.ToFrequencyFrct(p.vfo.TX.Frequency+p.vfo.TX.FilterWidth/2, p.frequencyRange),
 
 		FrequencyScale:     p.frequencyScale(),
 		DBScale:            p.dbScale(),
 		Spectrum:           spectrum,
 		SigmaEnvelope:      sigmaEnvelope,
+		HoldSpectrum:       p.holdSpectrum(),
 		PeakThresholdLevel: core.ToDBFrct(core.DB(p.fft.PeakThreshold), p.dbRange),
+		NoiseFloorLevel:    core.ToDBFrct(core.DB(p.fft.NoiseFloor), p.dbRange),
 		Waterline:          p.waterline(spectrum),
+		Overload:           p.overload(),
 	}
 	if p.signalDetectionActive {
 		result.Peaks = p.peaks()
 	}
+	result.Spots = p.spotMarks(time.Now())
+	result.SkimmerSpots = p.skimmerSpotMarks(time.Now())
+	result.Bookmarks = p.bookmarkMarks()
 
 	return result
 }
 
+func (p Panorama) overload() bool {
+	return p.fft.Clipping > overloadThreshold
+}
+
 func (p Panorama) signalLevel() core.DB {
 	vfoIndex := p.fft.ToIndex(p.vfo.Frequency)
 	if vfoIndex >= 0 && vfoIndex < len(p.fft.Data) {
@@ -394,6 +553,18 @@ func (p Panorama) dbScale() []core.DBMark {
 }
 
 func (p Panorama) spectrum() ([]core.FPoint, []core.FPoint) {
+	return p.trace(p.fft.Data), p.trace(p.fft.SigmaEnvelope)
+}
+
+func (p Panorama) holdSpectrum() []core.FPoint {
+	if len(p.fft.Hold) != len(p.fft.Data) {
+		return nil
+	}
+	return p.trace(p.fft.Hold)
+}
+
+// trace returns the points of the given FFT values within the visible frequency range, reduced to the panorama's width.
+func (p Panorama) trace(values []float64) []core.FPoint {
 	fftResolution := p.fft.Resolution()
 	step := int(math.Max(1, math.Floor(float64(len(p.fft.Data))/float64(p.width))))
 	start := int(math.Max(0, math.Floor(float64(p.frequencyRange.From-p.fft.Range.From)/fftResolution)))
@@ -404,31 +575,26 @@ func (p Panorama) spectrum() ([]core.FPoint, []core.FPoint) {
 	}
 
 	result := make([]core.FPoint, resultLength)
-	sigmaEnvelope := make([]core.FPoint, resultLength)
 	resultIndex := 0
 	for i := start; i <= end; i += step {
 		d := -1000.0
-		t := -1000.0
-		for j := i; j < i+step && j < len(p.fft.Data); j++ {
-			d = math.Max(d, p.fft.Data[j])
-			t = math.Max(t, p.fft.SigmaEnvelope[j])
+		for j := i; j < i+step && j < len(values); j++ {
+			d = math.Max(d, values[j])
 		}
 
 		result[resultIndex] = core.FPoint{
 			X: core.ToFrequencyFrct(p.fft.Frequency(i), p.frequencyRange),
 			Y: core.ToDBFrct(core.DB(d), p.dbRange),
 		}
-		sigmaEnvelope[resultIndex] = core.FPoint{
-			X: core.ToFrequencyFrct(p.fft.Frequency(i), p.frequencyRange),
-			Y: core.ToDBFrct(core.DB(t), p.dbRange),
-		}
 		resultIndex++
 	}
 
-	return result, sigmaEnvelope
+	return result
 }
 
-func (p Panorama) peaks() []core.PeakMark {
+// updatePeaks merges the peaks of the current FFT into the peak buffer. Peaks that were not seen for longer than the
+// peak timeout are removed from the buffer and written into the activity log.
+func (p *Panorama) updatePeaks(now time.Time) {
 	correction := func(i int) core.Frequency {
 		if i <= 0 || i >= len(p.fft.Data)-1 {
 			return 0
@@ -436,40 +602,179 @@ func (p Panorama) peaks() []core.PeakMark {
 		return core.Frequency((p.fft.Data[i+1] - p.fft.Data[i-1]) / (4*p.fft.Data[i] - 2*p.fft.Data[i-1] - 2*p.fft.Data[i+1]))
 	}
 
-	now := time.Now()
 	for _, peakIndexRange := range p.fft.Peaks {
 		peak := peak{
 			frequencyRange: core.FrequencyRange{From: p.fft.Frequency(peakIndexRange.From), To: p.fft.Frequency(peakIndexRange.To)},
 			maxFrequency:   p.fft.Frequency(peakIndexRange.Max) + correction(peakIndexRange.Max),
 			valueDB:        core.DB(peakIndexRange.Value),
+			maxValueDB:     core.DB(peakIndexRange.Value),
+			snr:            core.DB(peakIndexRange.SNR),
+			twoTone:        twoTone(p.fft, peakIndexRange),
+			firstSeen:      now,
 			lastSeen:       now,
 		}
 		key := toPeakKey(peak.maxFrequency)
+		if existing, ok := p.peakBuffer[key]; ok && now.Sub(existing.lastSeen) < p.peakTimeout {
+			peak.firstSeen = existing.firstSeen
+			peak.maxValueDB = core.DB(math.Max(float64(existing.maxValueDB), float64(peak.valueDB)))
+		}
 		p.peakBuffer[key] = peak
 	}
+	for key, text := range p.cwTexts {
+		if now.Sub(text.received) >= p.peakTimeout {
+			delete(p.cwTexts, key)
+		}
+	}
 
-	result := make([]core.PeakMark, 0, len(p.fft.Peaks))
+	slotActivity := slotActivity(p.peakBuffer)
 	for key, peak := range p.peakBuffer {
-		age := now.Sub(peak.lastSeen)
-		if age < p.peakTimeout && p.frequencyRange.Contains(peak.maxFrequency) {
-			result = append(result, core.PeakMark{
-				FromX:        core.ToFrequencyFrct(peak.frequencyRange.From, p.frequencyRange),
-				ToX:          core.ToFrequencyFrct(peak.frequencyRange.To, p.frequencyRange),
-				MaxX:         core.ToFrequencyFrct(peak.maxFrequency, p.frequencyRange),
-				MaxFrequency: peak.maxFrequency,
-				ValueY:       core.ToDBFrct(peak.valueDB, p.dbRange),
-				ValueDB:      peak.valueDB,
-			})
-		} else if age > 0 {
-			p.peakBuffer[key] = peak
-		} else if age >= p.peakTimeout || !p.frequencyRange.Contains(peak.maxFrequency) {
+		if now.Sub(peak.lastSeen) >= p.peakTimeout {
 			delete(p.peakBuffer, key)
+			p.logActivity(peak)
+			continue
+		}
+		peak.class = classify(peak, slotActivity)
+		p.peakBuffer[key] = peak
+	}
+}
+
+// FlushActivityLog writes all peaks that are still in the peak buffer into the activity log.
+func (p *Panorama) FlushActivityLog() {
+	for key, peak := range p.peakBuffer {
+		delete(p.peakBuffer, key)
+		p.logActivity(peak)
+	}
+}
+
+func (p Panorama) peaks() []core.PeakMark {
+	result := make([]core.PeakMark, 0, len(p.peakBuffer))
+	for _, peak := range p.peakBuffer {
+		if !p.frequencyRange.Contains(peak.maxFrequency) {
+			continue
+		}
+		text := p.cwText(peak.maxFrequency)
+		result = append(result, core.PeakMark{
+			FromX:         core.ToFrequencyFrct(peak.frequencyRange.From, p.frequencyRange),
+			ToX:           core.ToFrequencyFrct(peak.frequencyRange.To, p.frequencyRange),
+			MaxX:          core.ToFrequencyFrct(peak.maxFrequency, p.frequencyRange),
+			MaxFrequency:  peak.maxFrequency,
+			ValueY:        core.ToDBFrct(peak.valueDB, p.dbRange),
+			ValueDB:       peak.valueDB,
+			SNR:           peak.snr,
+			Class:         peak.class,
+			TuneFrequency: tuneFrequency(peak, peak.class),
+			CWText:        text.Text,
+			Callsign:      text.Callsign,
+		})
+	}
+
+	return result
+}
+
+func (p Panorama) spotMarks(now time.Time) []core.SpotMark {
+	result := make([]core.SpotMark, 0, len(p.spots))
+	for call, spot := range p.spots {
+		if now.Sub(spot.Time) >= p.spotTimeout {
+			delete(p.spots, call)
+			continue
+		}
+		if !p.frequencyRange.Contains(spot.Frequency) {
+			continue
+		}
+		result = append(result, core.SpotMark{
+			X:         core.ToFrequencyFrct(spot.Frequency, p.frequencyRange),
+			Frequency: spot.Frequency,
+			Call:      spot.Call,
+			Mode:      spot.Mode,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Frequency < result[j].Frequency
+	})
+	return result
+}
+
+func (p Panorama) skimmerSpotMarks(now time.Time) []core.SkimmerSpotMark {
+	result := make([]core.SkimmerSpotMark, 0, len(p.skimmerSpots))
+	for call, spots := range p.skimmerSpots {
+		active := spots[:0]
+		for _, spot := range spots {
+			if now.Sub(spot.lastSeen) >= p.spotTimeout {
+				continue
+			}
+			active = append(active, spot)
+			if !p.frequencyRange.Contains(spot.frequency) {
+				continue
+			}
+			result = append(result, core.SkimmerSpotMark{
+				X:         core.ToFrequencyFrct(spot.frequency, p.frequencyRange),
+				Frequency: spot.frequency,
+				Call:      call,
+				SNR:       spot.snr,
+				WPM:       spot.wpm,
+				Spotters:  len(spot.spotters),
+			})
+		}
+		if len(active) == 0 {
+			delete(p.skimmerSpots, call)
+		} else {
+			p.skimmerSpots[call] = active
 		}
 	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Frequency < result[j].Frequency
+	})
+	return result
+}
 
+func (p Panorama) bookmarkMarks() []core.BookmarkMark {
+	result := make([]core.BookmarkMark, 0, len(p.bookmarks))
+	for _, bookmark := range p.bookmarks {
+		if !p.frequencyRange.Contains(bookmark.Frequency) {
+			continue
+		}
+		result = append(result, core.BookmarkMark{
+			X:        core.ToFrequencyFrct(bookmark.Frequency, p.frequencyRange),
+			Bookmark: bookmark,
+		})
+	}
+	return result
+}
+
+// cwText returns the CW text that was decoded nearest to the given frequency.
+func (p Panorama) cwText(f core.Frequency) core.CWText {
+	var result core.CWText
+	distance := cwTextTolerance
+	key := toPeakKey(f)
+	for k := key - 1; k <= key+1; k++ {
+		text, ok := p.cwTexts[k]
+		if !ok {
+			continue
+		}
+		d := core.Frequency(math.Abs(float64(text.Frequency - f)))
+		if d <= distance {
+			result = text.CWText
+			distance = d
+		}
+	}
 	return result
 }
 
+func (p Panorama) logActivity(peak peak) {
+	if p.activityLog == nil {
+		return
+	}
+	p.activityLog.Log(core.SignalActivity{
+		FirstSeen: peak.firstSeen,
+		LastSeen:  peak.lastSeen,
+		Frequency: peak.frequencyRange.Center(),
+		Bandwidth: peak.frequencyRange.Width(),
+		MaxLevel:  peak.maxValueDB,
+		Band:      p.plan.ByFrequency(peak.maxFrequency).Name,
+		Class:     peak.class,
+	})
+}
+
 func (p Panorama) waterline(spectrum []core.FPoint) []core.Frct {
 	length := int(p.width)
 	binWidth := float64(length) / float64(len(spectrum))
@@ -2,14 +2,17 @@ package panorama
 
 import (
 	"testing"
+	"time"
 
+	"github.com/ftl/hamradio/bandplan"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/ftl/panacotta/core"
+	"github.com/ftl/panacotta/core/iaru"
 )
 
 func TestWidth(t *testing.T) {
-	p := New(100, core.FrequencyRange{1000.0, 1200.0}, 1100.0)
+	p := New(100, core.FrequencyRange{From: 1000.0, To: 1200.0}, 1100.0)
 
 	p.SetSize(200, 100)
 
@@ -23,7 +26,7 @@ func TestWidth(t *testing.T) {
 	assert.Equal(t, core.Frequency(1050.0), p.From())
 	assert.Equal(t, core.Frequency(1150.0), p.To())
 
-	p.SetVFO(core.VFO{"A", 1130.0, 10.0, ""})
+	p.SetVFO(core.VFO{Name: "A", Frequency: 1130.0, FilterWidth: 10.0})
 	p.SetSize(100, 100)
 
 	assert.Equal(t, core.Px(100), p.width)
@@ -32,10 +35,10 @@ func TestWidth(t *testing.T) {
 }
 
 func TestToggleViewMode(t *testing.T) {
-	p := New(100, core.FrequencyRange{1000.0, 1200.0}, 1100.0)
-	p.resolution[ViewCentered] = 1
+	p := New(100, core.FrequencyRange{From: 1000.0, To: 1200.0}, 1100.0)
+	p.resolution[core.ViewCentered] = 1
 
-	p.SetVFO(core.VFO{"A", 1150.0, 10.0, ""})
+	p.SetVFO(core.VFO{Name: "A", Frequency: 1150.0, FilterWidth: 10.0})
 
 	assert.Equal(t, core.Frequency(1000.0), p.From())
 	assert.Equal(t, core.Frequency(1200.0), p.To())
@@ -52,38 +55,38 @@ func TestToggleViewMode(t *testing.T) {
 }
 
 func TestCenteredVFO(t *testing.T) {
-	p := New(100, core.FrequencyRange{1000.0, 1200.0}, 1100.0)
-	p.resolution[ViewCentered] = 1
-	p.viewMode = ViewCentered
+	p := New(100, core.FrequencyRange{From: 1000.0, To: 1200.0}, 1100.0)
+	p.resolution[core.ViewCentered] = 1
+	p.viewMode = core.ViewCentered
 
-	p.SetVFO(core.VFO{"A", 1150.0, 10.0, ""})
+	p.SetVFO(core.VFO{Name: "A", Frequency: 1150.0, FilterWidth: 10.0})
 
 	assert.Equal(t, core.Frequency(1100.0), p.From())
 	assert.Equal(t, core.Frequency(1200.0), p.To())
 }
 
 func TestFixedVFO(t *testing.T) {
-	p := New(100, core.FrequencyRange{1000.0, 1200.0}, 1100.0)
-	p.viewMode = ViewFixed
+	p := New(100, core.FrequencyRange{From: 1000.0, To: 1200.0}, 1100.0)
+	p.viewMode = core.ViewFixed
 
-	p.SetVFO(core.VFO{"A", 1150.0, 10.0, ""})
+	p.SetVFO(core.VFO{Name: "A", Frequency: 1150.0, FilterWidth: 10.0})
 
 	assert.Equal(t, core.Frequency(1000.0), p.From())
 	assert.Equal(t, core.Frequency(1200.0), p.To())
 
-	p.SetVFO(core.VFO{"A", 1199.0, 10.0, ""})
+	p.SetVFO(core.VFO{Name: "A", Frequency: 1199.0, FilterWidth: 10.0})
 
 	assert.Equal(t, core.Frequency(1003.0), p.From())
 	assert.Equal(t, core.Frequency(1203.0), p.To())
 
-	p.SetVFO(core.VFO{"A", 2000.0, 10.0, ""})
+	p.SetVFO(core.VFO{Name: "A", Frequency: 2000.0, FilterWidth: 10.0})
 
 	assert.Equal(t, core.Frequency(1900.0), p.From())
 	assert.Equal(t, core.Frequency(2100.0), p.To())
 }
 
 func TestZoom(t *testing.T) {
-	p := New(1000, core.FrequencyRange{100000.0, 120000.0}, 110000.0)
+	p := New(1000, core.FrequencyRange{From: 100000.0, To: 120000.0}, 110000.0)
 
 	p.ZoomIn()
 
@@ -95,12 +98,12 @@ func TestZoom(t *testing.T) {
 	assert.Equal(t, core.Frequency(100000.0), p.From())
 	assert.Equal(t, core.Frequency(120000.0), p.To())
 
-	p.viewMode = ViewCentered
-	p.zoomTo(core.FrequencyRange{110000.0, 115000.0})
+	p.viewMode = core.ViewCentered
+	p.zoomTo(core.FrequencyRange{From: 110000.0, To: 115000.0})
 
 	assert.Equal(t, core.Frequency(110000.0), p.From())
 	assert.Equal(t, core.Frequency(115000.0), p.To())
-	assert.Equal(t, ViewFixed, p.viewMode)
+	assert.Equal(t, core.ViewFixed, p.viewMode)
 	assert.Equal(t, core.HzPerPx(5.0), p.resolution[p.viewMode])
 
 	p.ResetZoom()
@@ -109,48 +112,289 @@ func TestZoom(t *testing.T) {
 	assert.Equal(t, defaultFixedResolution, p.resolution[p.viewMode])
 }
 
-func TestDrag(t *testing.T) {
-	p := New(1000, core.FrequencyRange{100000.0, 120000.0}, 110000.0)
+func TestShiftFrequencyRange(t *testing.T) {
+	p := New(1000, core.FrequencyRange{From: 100000.0, To: 120000.0}, 110000.0)
 
-	p.Drag(-10000.0)
+	p.ShiftFrequencyRange(-0.5)
 
 	assert.Equal(t, core.Frequency(90000.0), p.From())
 	assert.Equal(t, core.Frequency(110000.0), p.To())
 
-	p.Drag(10000.0)
+	p.ShiftFrequencyRange(0.5)
 
 	assert.Equal(t, core.Frequency(100000.0), p.From())
 	assert.Equal(t, core.Frequency(120000.0), p.To())
 }
 
 func TestFrequencyScale(t *testing.T) {
-	p := New(1000, core.FrequencyRange{100300.0, 120700.0}, 110000.0)
+	p := New(1000, core.FrequencyRange{From: 100300.0, To: 120700.0}, 110000.0)
 
 	scale1 := p.frequencyScale()
-	offset1 := int(scale1[1].X - scale1[0].X)
 
-	assert.Equal(t, 5, len(scale1))
-	assert.True(t, offset1 > 200)
-	assert.True(t, scale1[1].X-scale1[0].X < 300)
+	assert.Equal(t, 21, len(scale1))
+	assert.Equal(t, core.Frequency(1000), scale1[1].Frequency-scale1[0].Frequency)
+	assert.InDelta(t, 1000.0/20400.0, float64(scale1[1].X-scale1[0].X), 1e-9)
 
 	p.SetSize(2000, 100)
 	scale2 := p.frequencyScale()
-	offset2 := int(scale2[1].X - scale2[0].X)
 
 	assert.Equal(t, 9, len(scale2))
-	assert.Equal(t, offset1, offset2)
+	assert.Equal(t, core.Frequency(5000), scale2[1].Frequency-scale2[0].Frequency)
+	assert.InDelta(t, 5000.0/40800.0, float64(scale2[1].X-scale2[0].X), 1e-9)
 }
 
 func TestDBScale(t *testing.T) {
-	p := New(1000, core.FrequencyRange{100300.0, 120700.0}, 110000.0)
-	p.dbRange = core.DBRange{-125, 15}
+	p := New(1000, core.FrequencyRange{From: 100300.0, To: 120700.0}, 110000.0)
+	p.dbRange = core.DBRange{From: -125, To: 15}
 	p.SetSize(1000, 500)
 
 	dbScale := p.dbScale()
 
 	assert.Equal(t, 14, len(dbScale))
 	assert.Equal(t, core.DB(-120), dbScale[0].DB)
-	assert.Equal(t, 17, int(dbScale[0].Y))
+	assert.InDelta(t, 5.0/140.0, float64(dbScale[0].Y), 1e-9)
 	assert.Equal(t, core.DB(10), dbScale[13].DB)
-	assert.Equal(t, 482, int(dbScale[13].Y))
+	assert.InDelta(t, 135.0/140.0, float64(dbScale[13].Y), 1e-9)
+}
+
+func TestOverload(t *testing.T) {
+	p := New(1000, core.FrequencyRange{From: 100000.0, To: 120000.0}, 110000.0)
+	p.SetFFT(core.FFT{Data: make([]float64, 100), Range: core.FrequencyRange{From: 100000.0, To: 120000.0}, SigmaEnvelope: make([]float64, 100)})
+
+	assert.False(t, p.Data().Overload)
+
+	p.fft.Clipping = 0.01
+
+	assert.True(t, p.Data().Overload)
+}
+
+func TestInputState(t *testing.T) {
+	p := New(1000, core.FrequencyRange{From: 100000.0, To: 120000.0}, 110000.0)
+
+	assert.Equal(t, core.InputRunning, p.Data().InputState)
+
+	p.SetInputState(core.InputReconnecting)
+
+	assert.Equal(t, core.InputReconnecting, p.Data().InputState)
+}
+
+func TestHoldSpectrum(t *testing.T) {
+	p := New(100, core.FrequencyRange{From: 100000.0, To: 120000.0}, 110000.0)
+	fft := core.FFT{Data: make([]float64, 100), Range: core.FrequencyRange{From: 100000.0, To: 120000.0}, SigmaEnvelope: make([]float64, 100)}
+	p.SetFFT(fft)
+
+	assert.Empty(t, p.Data().HoldSpectrum)
+
+	fft.Hold = make([]float64, 100)
+	p.SetFFT(fft)
+
+	assert.Equal(t, len(p.Data().Spectrum), len(p.Data().HoldSpectrum))
+}
+
+func TestPeakSNR(t *testing.T) {
+	p := New(100, core.FrequencyRange{From: 100000.0, To: 120000.0}, 110000.0)
+	fft := singlePeakFFT(core.FrequencyRange{From: 100000.0, To: 120000.0})
+	fft.NoiseFloor = -100
+	fft.Peaks[0] = core.PeakIndexRange{From: 40, To: 60, Max: 50, Value: -70, SNR: 30}
+	p.SetFFT(fft)
+
+	data := p.Data()
+
+	assert.Equal(t, core.ToDBFrct(-100, p.dbRange), data.NoiseFloorLevel)
+	if assert.Equal(t, 1, len(data.Peaks)) {
+		assert.Equal(t, core.DB(30), data.Peaks[0].SNR)
+	}
+}
+
+func TestActivityLog(t *testing.T) {
+	p := New(100, core.FrequencyRange{From: 7000000.0, To: 7020000.0}, 7010000.0)
+	logger := &activityRecorder{}
+	p.SetActivityLog(logger)
+	p.SetPeakPersistence(10 * time.Millisecond)
+	fft := singlePeakFFT(core.FrequencyRange{From: 7000000.0, To: 7020000.0})
+
+	p.SetFFT(fft)
+	fft.Peaks[0].Value = -60
+	p.SetFFT(fft)
+
+	assert.Empty(t, logger.activities)
+
+	time.Sleep(20 * time.Millisecond)
+	fft.Peaks = nil
+	p.SetFFT(fft)
+
+	if assert.Equal(t, 1, len(logger.activities)) {
+		activity := logger.activities[0]
+		assert.Equal(t, core.DB(-60), activity.MaxLevel)
+		assert.Equal(t, bandplan.Band40m, activity.Band)
+		assert.False(t, activity.LastSeen.Before(activity.FirstSeen))
+	}
+	assert.Empty(t, p.peakBuffer)
+
+	fft.Peaks = []core.PeakIndexRange{{From: 49, To: 51, Max: 50, Value: -70}}
+	p.SetFFT(fft)
+	p.FlushActivityLog()
+
+	assert.Equal(t, 2, len(logger.activities), "remaining peaks are logged when flushed")
+	assert.Empty(t, p.peakBuffer)
+}
+
+func TestCWText(t *testing.T) {
+	p := New(100, core.FrequencyRange{From: 7000000.0, To: 7020000.0}, 7010000.0)
+	p.SetFFT(singlePeakFFT(core.FrequencyRange{From: 7000000.0, To: 7020000.0}))
+	peakFrequency := p.fft.Frequency(50)
+
+	p.SetCWText(core.CWText{Frequency: peakFrequency + 300, Text: "TEST K1A"})
+	p.SetCWText(core.CWText{Frequency: peakFrequency + 20, Text: "CQ DL1ABC", Callsign: "DL1ABC"})
+	data := p.Data()
+
+	if assert.Equal(t, 1, len(data.Peaks)) {
+		assert.Equal(t, "CQ DL1ABC", data.Peaks[0].CWText)
+		assert.Equal(t, "DL1ABC", data.Peaks[0].Callsign)
+	}
+
+	p.SetPeakPersistence(0)
+	p.SetFFT(core.FFT{Data: p.fft.Data, Range: p.fft.Range, SigmaEnvelope: p.fft.SigmaEnvelope})
+	assert.Empty(t, p.cwTexts, "CW texts expire with the peaks")
+}
+
+func TestSpots(t *testing.T) {
+	p := New(100, core.FrequencyRange{From: 7000000.0, To: 7020000.0}, 7010000.0)
+	p.SetSpotMaxAge(10 * time.Minute)
+	now := time.Now()
+	p.SetSpot(core.Spot{Call: "K1A", Frequency: 7015000, Mode: "CW", Time: now.Add(-time.Minute)})
+	p.SetSpot(core.Spot{Call: "DL1ABC", Frequency: 7012000, Time: now.Add(-20 * time.Minute)})
+	p.SetSpot(core.Spot{Call: "EA8XYZ", Frequency: 7185500, Mode: "LSB", Time: now})
+	p.SetSpot(core.Spot{Call: "K1A", Frequency: 7005000, Mode: "CW", Time: now})
+
+	spots := p.spotMarks(now)
+
+	assert.Equal(t, []core.SpotMark{{X: 0.25, Frequency: 7005000, Call: "K1A", Mode: "CW"}}, spots)
+	assert.Equal(t, 2, len(p.spots), "expired spots are removed")
+}
+
+func TestSkimmerSpots(t *testing.T) {
+	p := New(100, core.FrequencyRange{From: 7000000.0, To: 7040000.0}, 7010000.0)
+	p.SetSpotMaxAge(10 * time.Minute)
+	now := time.Now()
+	p.SetSkimmerSpot(core.Spot{Call: "DL1ABC", Frequency: 7023500, Spotter: "EA5WU", SNR: 22, WPM: 28, Time: now.Add(-2 * time.Minute)})
+	p.SetSkimmerSpot(core.Spot{Call: "DL1ABC", Frequency: 7023520, Spotter: "DK9IP", SNR: 31, WPM: 29, Time: now.Add(-time.Minute)})
+	p.SetSkimmerSpot(core.Spot{Call: "DL1ABC", Frequency: 7023540, Spotter: "EA5WU", SNR: 12, Time: now.Add(-time.Minute)})
+	p.SetSkimmerSpot(core.Spot{Call: "K1A", Frequency: 7010000, Spotter: "W3LPL", SNR: 5, WPM: 35, Time: now.Add(-11 * time.Minute)})
+
+	spots := p.skimmerSpotMarks(now)
+
+	assert.Equal(t, []core.SkimmerSpotMark{
+		{X: 0.588, Frequency: 7023520, Call: "DL1ABC", SNR: 31, WPM: 29, Spotters: 2},
+	}, spots)
+	assert.Equal(t, 1, len(p.skimmerSpots), "expired spots are removed")
+}
+
+func TestSkimmerSpotsWithinTolerance(t *testing.T) {
+	p := New(100, core.FrequencyRange{From: 7000000.0, To: 7040000.0}, 7010000.0)
+	p.SetSpotMaxAge(10 * time.Minute)
+	now := time.Now()
+	p.SetSkimmerSpot(core.Spot{Call: "DL1ABC", Frequency: 7023450, Spotter: "EA5WU", SNR: 22, Time: now})
+	p.SetSkimmerSpot(core.Spot{Call: "DL1ABC", Frequency: 7023550, Spotter: "DK9IP", SNR: 31, Time: now})
+	p.SetSkimmerSpot(core.Spot{Call: "DL1ABC", Frequency: 7024500, Spotter: "W3LPL", SNR: 12, Time: now})
+
+	spots := p.skimmerSpotMarks(now)
+
+	if assert.Equal(t, 2, len(spots), "spots of two spotters 100Hz apart are merged") {
+		assert.Equal(t, core.Frequency(7023500), spots[0].Frequency)
+		assert.Equal(t, 2, spots[0].Spotters)
+		assert.Equal(t, core.Frequency(7024500), spots[1].Frequency)
+		assert.Equal(t, 1, spots[1].Spotters)
+	}
+}
+
+func TestBookmarks(t *testing.T) {
+	p := New(100, core.FrequencyRange{From: 7000000.0, To: 7020000.0}, 7010000.0)
+	p.SetBookmarks([]core.Bookmark{
+		{Frequency: 7005000, Label: "M1"},
+		{Frequency: 7074000, Label: "FT8"},
+	})
+
+	assert.Equal(t, []core.BookmarkMark{{X: 0.25, Bookmark: core.Bookmark{Frequency: 7005000, Label: "M1"}}}, p.bookmarkMarks())
+}
+
+func TestSegmentWarning(t *testing.T) {
+	p := New(100, core.FrequencyRange{From: 3900000.0, To: 4000000.0}, 3950000.0)
+	p.SetFFT(core.FFT{Data: make([]float64, 100), Range: core.FrequencyRange{From: 3900000.0, To: 4000000.0}})
+	p.SetBandplan(iaru.Region2)
+	p.SetVFO(core.VFO{Frequency: 3950000, Mode: "LSB"})
+
+	assert.Equal(t, bandplan.Band80m, p.band.Name)
+	assert.False(t, p.Data().VFOOutOfSegment, "the warning is disabled")
+
+	p.SetSegmentWarning(true)
+	assert.False(t, p.Data().VFOOutOfSegment)
+
+	p.SetVFO(core.VFO{Frequency: 3950000, Mode: "CW"})
+	assert.True(t, p.Data().VFOOutOfSegment)
+}
+
+func TestTXVFO(t *testing.T) {
+	p := New(100, core.FrequencyRange{From: 7000000.0, To: 7020000.0}, 7010000.0)
+	p.SetFFT(core.FFT{Data: make([]float64, 100), Range: core.FrequencyRange{From: 7000000.0, To: 7020000.0}})
+	p.SetVFO(core.VFO{Frequency: 7010000, FilterWidth: 500, Split: true, TX: core.TXVFO{Frequency: 7015000, FilterWidth: 1000}})
+
+	data := p.Data()
+
+	assert.True(t, data.VFO.Split)
+	assert.InDelta(t, 0.75, float64(data.TXVFOLine), 0.0001)
+	assert.InDelta(t, 0.725, float64(data.TXVFOFilterFrom), 0.0001)
+	assert.InDelta(t, 0.775, float64(data.TXVFOFilterTo), 0.0001)
+}
+
+func TestFreezeOnTX(t *testing.T) {
+	p := New(100, core.FrequencyRange{From: 7000000.0, To: 7020000.0}, 7010000.0)
+	p.SetFreezeOnTX(true)
+	fft := singlePeakFFT(core.FrequencyRange{From: 7000000.0, To: 7020000.0})
+	fft.Hold = append([]float64{}, fft.Data...)
+	p.SetFFT(fft)
+	holdBeforeTX := p.Data().HoldSpectrum
+
+	p.SetVFO(core.VFO{Frequency: 7010000, PTT: true})
+	flooded := make([]float64, 100)
+	p.SetFFT(core.FFT{Data: flooded, Range: fft.Range, SigmaEnvelope: flooded, Hold: flooded})
+	p.SetPeakPersistence(0)
+	data := p.Data()
+
+	assert.True(t, data.Frozen)
+	assert.Equal(t, -100.0, p.fft.Data[0], "the spectrum is frozen")
+	assert.NotEmpty(t, holdBeforeTX)
+	assert.Equal(t, holdBeforeTX, data.HoldSpectrum, "the hold trace survives the transmission")
+	assert.Equal(t, 1, len(data.Peaks), "the peak buffer is not updated")
+
+	p.SetVFO(core.VFO{Frequency: 7010000})
+	p.SetFFT(core.FFT{Data: flooded, Range: fft.Range, SigmaEnvelope: flooded})
+	data = p.Data()
+
+	assert.False(t, data.Frozen)
+	assert.Equal(t, 0.0, p.fft.Data[0])
+	assert.Empty(t, data.Peaks)
+}
+
+// singlePeakFFT returns an FFT of 100 bins at -100dB with a single peak of -70dB in the center of the given range.
+func singlePeakFFT(frequencyRange core.FrequencyRange) core.FFT {
+	spectrum := make([]float64, 100)
+	for i := range spectrum {
+		spectrum[i] = -100
+	}
+	spectrum[49], spectrum[50], spectrum[51] = -80, -70, -80
+	return core.FFT{
+		Data:          spectrum,
+		Range:         frequencyRange,
+		SigmaEnvelope: make([]float64, 100),
+		Peaks:         []core.PeakIndexRange{{From: 49, To: 51, Max: 50, Value: -70}},
+	}
+}
+
+type activityRecorder struct {
+	activities []core.SignalActivity
+}
+
+func (r *activityRecorder) Log(activity core.SignalActivity) {
+	r.activities = append(r.activities, activity)
 }
@@ -7,16 +7,74 @@ import (
 	"time"
 
 	rtl "github.com/jpoirier/gortlsdr"
+	"github.com/pkg/errors"
+
+	"github.com/ftl/panacotta/core"
+	"github.com/ftl/panacotta/core/dsp"
+)
+
+const (
+	watchdogInterval = 500 * time.Millisecond
+	stallTimeout     = 2 * time.Second
+	reconnectDelay   = 2 * time.Second
+	blockPoolSize    = 4
 )
 
-// Open the RTL-SDR dongle for reading.
-func Open(centerFrequency int, sampleRate int, blockSize int, frequencyCorrection int) (*Dongle, error) {
-	device, err := rtl.Open(0)
+// Device selects a dongle by its index or by its USB serial number. If a serial number is given, it takes precedence.
+type Device struct {
+	Index  int
+	Serial string
+}
+
+// Open the RTL-SDR dongle for reading. A watchdog reopens the dongle with the same parameters if it stops delivering samples.
+func Open(selection Device, centerFrequency int, sampleRate int, blockSize int, frequencyCorrection int, tuner core.TunerSettings) (*Dongle, error) {
+	result := Dongle{
+		selection:           selection,
+		centerFrequency:     centerFrequency,
+		sampleRate:          sampleRate,
+		blockSize:           blockSize,
+		frequencyCorrection: frequencyCorrection,
+		tuner:               tuner,
+
+		deviceLock: new(sync.Mutex),
+		asyncRead:  new(sync.WaitGroup),
+		inputLock:  new(sync.Mutex),
+		samples:    make(chan []complex128, 1),
+		blocks:     dsp.NewBlockPool(blockPoolSize),
+		clipping:   dsp.NewClippingRecord(),
+		sinkLock:   new(sync.RWMutex),
+		state:      make(chan core.InputState, 1),
+		done:       make(chan struct{}),
+		watchdog:   new(sync.WaitGroup),
+	}
+
+	device, err := result.openDevice()
 	if err != nil {
 		return nil, err
 	}
+	result.device = device
+	result.startReading()
+
+	result.watchdog.Add(1)
+	go result.watch()
 
-	err = device.SetSampleRate(sampleRate)
+	return &result, nil
+}
+
+func (d *Dongle) openDevice() (*rtl.Context, error) {
+	index, err := findDevice(d.selection)
+	if err != nil {
+		logDevices()
+		return nil, err
+	}
+	log.Printf("RTL-SDR dongle #%d: %s", index, rtl.GetDeviceName(index))
+
+	device, err := rtl.Open(index)
+	if err != nil {
+		return nil, err
+	}
+
+	err = device.SetSampleRate(d.sampleRate)
 	if err != nil {
 		device.Close()
 		log.Print("SetSampleRate failed", err)
@@ -24,7 +82,7 @@ func Open(centerFrequency int, sampleRate int, blockSize int, frequencyCorrectio
 	}
 	log.Printf("GetSampleRate: %d\n", device.GetSampleRate())
 
-	err = device.SetCenterFreq(centerFrequency)
+	err = device.SetCenterFreq(d.centerFrequency)
 	if err != nil {
 		device.Close()
 		log.Print("SetCenterFreq failed", err)
@@ -38,35 +96,82 @@ func Open(centerFrequency int, sampleRate int, blockSize int, frequencyCorrectio
 		return nil, err
 	}
 
-	err = device.SetFreqCorrection(frequencyCorrection)
+	err = device.SetFreqCorrection(d.frequencyCorrection)
 	if err != nil {
 		device.Close()
 		log.Print("SetFreqCorrection failed", err)
 		return nil, err
 	}
 
-	result := Dongle{
-		device:    device,
-		asyncRead: new(sync.WaitGroup),
-		samples:   make(chan []complex128, 1),
+	d.deviceLock.Lock()
+	tuner := d.tuner
+	d.deviceLock.Unlock()
+	err = applyTunerSettings(device, tuner)
+	if err != nil {
+		device.Close()
+		return nil, err
 	}
 
-	go func() {
-		result.asyncRead.Add(1)
-		result.device.ReadAsync(result.incomingData, nil, 0, blockSize*2)
-		result.asyncRead.Done()
-	}()
+	return device, nil
+}
 
-	return &result, nil
+func findDevice(selection Device) (int, error) {
+	count := rtl.GetDeviceCount()
+	if count == 0 {
+		return 0, errors.New("no RTL-SDR dongle found")
+	}
+
+	if selection.Serial != "" {
+		index, err := rtl.GetIndexBySerial(selection.Serial)
+		if err != nil {
+			return 0, errors.Wrapf(err, "no RTL-SDR dongle with serial %q", selection.Serial)
+		}
+		return index, nil
+	}
+
+	if selection.Index < 0 || selection.Index >= count {
+		return 0, errors.Errorf("no RTL-SDR dongle with index %d, %d dongles available", selection.Index, count)
+	}
+	return selection.Index, nil
+}
+
+func logDevices() {
+	count := rtl.GetDeviceCount()
+	log.Printf("%d RTL-SDR dongles available:", count)
+	for i := 0; i < count; i++ {
+		manufacturer, product, serial, err := rtl.GetDeviceUsbStrings(i)
+		if err != nil {
+			log.Printf("#%d: %s (%v)", i, rtl.GetDeviceName(i), err)
+			continue
+		}
+		log.Printf("#%d: %s, %s %s, serial %q", i, rtl.GetDeviceName(i), manufacturer, product, serial)
+	}
 }
 
 // Dongle represents the RTL-SDR dongle.
 type Dongle struct {
-	device    *rtl.Context
-	blockSize int
-	asyncRead *sync.WaitGroup
-	lastInput time.Time
-	samples   chan []complex128
+	selection           Device
+	centerFrequency     int
+	sampleRate          int
+	blockSize           int
+	frequencyCorrection int
+	tuner               core.TunerSettings
+
+	device     *rtl.Context
+	deviceLock *sync.Mutex
+	asyncRead  *sync.WaitGroup
+	lastInput  time.Time
+	inputLock  *sync.Mutex
+	samples    chan []complex128
+	blocks     *dsp.BlockPool
+	clipping   *dsp.ClippingRecord
+	rawSink    func([]byte)
+	sinkLock   *sync.RWMutex
+
+	currentState core.InputState
+	state        chan core.InputState
+	done         chan struct{}
+	watchdog     *sync.WaitGroup
 }
 
 // Samples from the dongle
@@ -74,34 +179,258 @@ func (d *Dongle) Samples() <-chan []complex128 {
 	return d.samples
 }
 
+// Release the given block of samples for reuse.
+func (d *Dongle) Release(block []complex128) {
+	d.clipping.Remove(block)
+	d.blocks.Put(block)
+}
+
+// Clipping returns the ratio of clipped raw samples in the given block.
+func (d *Dongle) Clipping(block []complex128) float64 {
+	return d.clipping.Get(block)
+}
+
+// InputState reports the state changes of the dongle. Only the latest state is kept if nobody is listening.
+func (d *Dongle) InputState() <-chan core.InputState {
+	return d.state
+}
+
 // Close the dongle.
 func (d *Dongle) Close() error {
 	defer log.Print("RTLSDR shutdown")
-	d.device.CancelAsync()
-	d.asyncRead.Wait()
+	close(d.done)
+	d.watchdog.Wait()
+
+	d.stopReading()
 	close(d.samples)
+
+	d.deviceLock.Lock()
+	defer d.deviceLock.Unlock()
+	if d.device == nil {
+		return nil
+	}
 	return d.device.Close()
 }
 
-func (d *Dongle) incomingData(data []byte) {
+func (d *Dongle) startReading() {
+	d.inputLock.Lock()
+	d.lastInput = time.Now()
+	d.inputLock.Unlock()
+
+	device := d.device
+	d.asyncRead.Add(1)
+	go func() {
+		defer d.asyncRead.Done()
+		err := device.ReadAsync(d.incomingData, nil, 0, d.blockSize*2)
+		if err != nil {
+			log.Printf("RTL-SDR async read failed: %v", err)
+		}
+	}()
+}
+
+func (d *Dongle) stopReading() {
+	d.deviceLock.Lock()
+	device := d.device
+	d.deviceLock.Unlock()
+
+	if device != nil {
+		device.CancelAsync()
+	}
+	d.asyncRead.Wait()
+}
+
+// watch the incoming samples and reopen the dongle when the samples stall.
+func (d *Dongle) watch() {
+	defer d.watchdog.Done()
+
+	tick := time.NewTicker(watchdogInterval)
+	defer tick.Stop()
+
+	d.setState(core.InputRunning)
+	for {
+		select {
+		case <-tick.C:
+			if d.stalled() {
+				d.setState(core.InputStalled)
+				d.reconnect()
+			}
+		case <-d.done:
+			return
+		}
+	}
+}
+
+func (d *Dongle) stalled() bool {
+	d.inputLock.Lock()
+	defer d.inputLock.Unlock()
+	return time.Since(d.lastInput) > stallTimeout
+}
+
+func (d *Dongle) reconnect() {
+	d.stopReading()
+
+	d.deviceLock.Lock()
+	if d.device != nil {
+		d.device.Close()
+		d.device = nil
+	}
+	d.deviceLock.Unlock()
+
+	d.setState(core.InputReconnecting)
+	for {
+		device, err := d.openDevice()
+		if err == nil {
+			d.deviceLock.Lock()
+			d.device = device
+			d.deviceLock.Unlock()
+
+			d.startReading()
+			d.setState(core.InputRunning)
+			return
+		}
+		log.Printf("cannot reopen the RTL-SDR dongle: %v", err)
+
+		select {
+		case <-time.After(reconnectDelay):
+		case <-d.done:
+			return
+		}
+	}
+}
+
+// setState is only called from the watchdog goroutine.
+func (d *Dongle) setState(state core.InputState) {
+	if state == d.currentState {
+		return
+	}
+	log.Printf("RTL-SDR dongle %s", state)
+	d.currentState = state
+
 	select {
-	case d.samples <- normalizeSamples(data):
-		d.lastInput = time.Now()
+	case <-d.state:
 	default:
-		log.Print("RTL buffer overflow, dropping incoming data")
 	}
+	d.state <- state
+}
+
+// SetTunerSettings applies the given settings to the tuner. The gain is snapped to the nearest gain value that is supported
+// by the tuner. The settings are kept and applied again when the dongle is reopened.
+func (d *Dongle) SetTunerSettings(settings core.TunerSettings) error {
+	d.deviceLock.Lock()
+	defer d.deviceLock.Unlock()
+
+	d.tuner = settings
+	if d.device == nil {
+		return nil
+	}
+	return applyTunerSettings(d.device, settings)
 }
 
-func normalizeSamples(block []byte) []complex128 {
-	result := make([]complex128, len(block)/2)
-	for i := 0; i < len(block); i += 2 {
-		qSample := normalizeSampleUint8(block[i])
-		iSample := normalizeSampleUint8(block[i+1])
-		result[i/2] = complex(iSample, qSample)
+func applyTunerSettings(device *rtl.Context, settings core.TunerSettings) error {
+	err := device.SetTunerGainMode(settings.ManualGain)
+	if err != nil {
+		log.Print("SetTunerGainMode failed", err)
+		return err
+	}
+
+	if settings.ManualGain {
+		gains, err := device.GetTunerGains()
+		if err != nil {
+			log.Print("GetTunerGains failed", err)
+			return err
+		}
+		gain := nearestGain(gains, int(math.Round(float64(settings.Gain)*10)))
+		err = device.SetTunerGain(gain)
+		if err != nil {
+			log.Print("SetTunerGain failed", err)
+			return err
+		}
+		log.Printf("tuner gain: %.1fdB", float64(gain)/10)
+	} else {
+		log.Print("tuner gain: auto")
+	}
+
+	err = device.SetAgcMode(settings.AGC)
+	if err != nil {
+		log.Print("SetAgcMode failed", err)
+		return err
+	}
+
+	err = device.SetTunerBw(settings.Bandwidth)
+	if err != nil {
+		log.Print("SetTunerBw failed", err)
+		return err
+	}
+
+	return nil
+}
+
+// nearestGain returns the value from the given list of gains that is closest to the given gain.
+func nearestGain(gains []int, gain int) int {
+	if len(gains) == 0 {
+		return gain
+	}
+	result := gains[0]
+	for _, g := range gains {
+		if abs(g-gain) < abs(result-gain) {
+			result = g
+		}
 	}
 	return result
 }
 
-func normalizeSampleUint8(s byte) float64 {
-	return (float64(s) - float64(math.MaxInt8)) / float64(math.MaxInt8)
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
+
+// TunerType of the dongle as reported by librtlsdr.
+func (d *Dongle) TunerType() string {
+	d.deviceLock.Lock()
+	defer d.deviceLock.Unlock()
+	if d.device == nil {
+		return ""
+	}
+	return d.device.GetTunerType()
+}
+
+// TunerGains returns the gain values supported by the tuner in tenths of a dB.
+func (d *Dongle) TunerGains() ([]int, error) {
+	d.deviceLock.Lock()
+	defer d.deviceLock.Unlock()
+	if d.device == nil {
+		return nil, errors.New("RTL-SDR dongle not available")
+	}
+	return d.device.GetTunerGains()
+}
+
+// SetRawDataSink sets a function that receives each block of raw data exactly as it comes from the dongle.
+// The data is only valid while the sink is called, the sink must not keep a reference to it.
+func (d *Dongle) SetRawDataSink(sink func([]byte)) {
+	d.sinkLock.Lock()
+	defer d.sinkLock.Unlock()
+	d.rawSink = sink
+}
+
+func (d *Dongle) incomingData(data []byte) {
+	d.inputLock.Lock()
+	d.lastInput = time.Now()
+	d.inputLock.Unlock()
+
+	d.sinkLock.RLock()
+	if d.rawSink != nil {
+		d.rawSink(data)
+	}
+	d.sinkLock.RUnlock()
+
+	samples := dsp.ConvertUint8SamplesInto(d.blocks.Get(len(data)/2), data)
+	d.clipping.Put(samples, dsp.ClippingUint8(data))
+	select {
+	case d.samples <- samples:
+	default:
+		d.Release(samples)
+		log.Print("RTL buffer overflow, dropping incoming data")
+	}
 }
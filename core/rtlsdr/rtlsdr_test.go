@@ -0,0 +1,30 @@
+package rtlsdr
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNearestGain(t *testing.T) {
+	gains := []int{0, 9, 14, 27, 37, 77, 87, 125, 144, 157, 166, 197, 207, 229, 254, 280, 297, 328, 338, 364, 372, 386, 402, 421, 434, 439, 445, 480, 496}
+	tt := []struct {
+		gains    []int
+		value    int
+		expected int
+	}{
+		{gains, -10, 0},
+		{gains, 0, 0},
+		{gains, 20, 14},
+		{gains, 21, 27},
+		{gains, 300, 297},
+		{gains, 1000, 496},
+		{[]int{}, 123, 123},
+	}
+	for _, tc := range tt {
+		t.Run(fmt.Sprintf("%d", tc.value), func(t *testing.T) {
+			assert.Equal(t, tc.expected, nearestGain(tc.gains, tc.value))
+		})
+	}
+}
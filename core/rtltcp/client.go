@@ -0,0 +1,200 @@
+package rtltcp
+
+import (
+	"io"
+	"log"
+	"math"
+	"net"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/ftl/panacotta/core"
+	"github.com/ftl/panacotta/core/dsp"
+)
+
+// Dial connects to the rtl_tcp server at the given address, configures the remote dongle and starts reading samples.
+func Dial(address string, centerFrequency int, sampleRate int, blockSize int, frequencyCorrection int, tuner core.TunerSettings) (*Client, error) {
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot open rtl_tcp connection")
+	}
+
+	info, err := readDongleInfo(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	log.Printf("rtl_tcp %s: tuner type %d, %d gain values", address, info.TunerType, info.GainCount)
+
+	result := Client{
+		conn:      conn,
+		info:      info,
+		blockSize: blockSize,
+		samples:   make(chan []complex128, 1),
+		blocks:    dsp.NewBlockPool(4),
+		clipping:  dsp.NewClippingRecord(),
+		asyncRead: new(sync.WaitGroup),
+	}
+
+	err = result.SetSampleRate(sampleRate)
+	if err == nil {
+		err = result.SetCenterFrequency(centerFrequency)
+	}
+	if err == nil && frequencyCorrection != 0 {
+		err = result.SetFrequencyCorrection(frequencyCorrection)
+	}
+	if err == nil {
+		err = result.SetTunerSettings(tuner)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	result.asyncRead.Add(1)
+	go result.readSamples()
+
+	return &result, nil
+}
+
+// Client of a rtl_tcp server.
+type Client struct {
+	conn      net.Conn
+	info      DongleInfo
+	blockSize int
+	samples   chan []complex128
+	blocks    *dsp.BlockPool
+	clipping  *dsp.ClippingRecord
+	asyncRead *sync.WaitGroup
+	writeLock sync.Mutex
+	closed    bool
+}
+
+func (c *Client) readSamples() {
+	defer c.asyncRead.Done()
+	block := make([]byte, c.blockSize*2)
+	for {
+		_, err := io.ReadFull(c.conn, block)
+		if err != nil {
+			if !c.isClosed() {
+				log.Printf("rtl_tcp connection lost: %v", err)
+			}
+			return
+		}
+
+		samples := dsp.ConvertUint8SamplesInto(c.blocks.Get(c.blockSize), block)
+		c.clipping.Put(samples, dsp.ClippingUint8(block))
+		select {
+		case c.samples <- samples:
+		default:
+			c.Release(samples)
+			log.Print("rtl_tcp buffer overflow, dropping incoming data")
+		}
+	}
+}
+
+func (c *Client) isClosed() bool {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	return c.closed
+}
+
+// DongleInfo as reported by the server.
+func (c *Client) DongleInfo() DongleInfo {
+	return c.info
+}
+
+// Samples from the remote dongle.
+func (c *Client) Samples() <-chan []complex128 {
+	return c.samples
+}
+
+// Release the given block of samples for reuse.
+func (c *Client) Release(block []complex128) {
+	c.clipping.Remove(block)
+	c.blocks.Put(block)
+}
+
+// Clipping returns the ratio of clipped raw samples in the given block.
+func (c *Client) Clipping(block []complex128) float64 {
+	return c.clipping.Get(block)
+}
+
+// Close the connection.
+func (c *Client) Close() error {
+	defer log.Print("rtl_tcp shutdown")
+	c.writeLock.Lock()
+	c.closed = true
+	err := c.conn.Close()
+	c.writeLock.Unlock()
+
+	c.asyncRead.Wait()
+	close(c.samples)
+	return err
+}
+
+func (c *Client) send(command Command, param uint32) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	if c.closed {
+		return errors.New("rtl_tcp connection closed")
+	}
+	err := writeCommand(c.conn, command, param)
+	if err != nil {
+		return errors.Wrapf(err, "cannot send rtl_tcp command %d", command)
+	}
+	return nil
+}
+
+// SetCenterFrequency of the remote dongle in Hz.
+func (c *Client) SetCenterFrequency(frequency int) error {
+	return c.send(SetFrequency, uint32(frequency))
+}
+
+// SetSampleRate of the remote dongle in samples per second.
+func (c *Client) SetSampleRate(sampleRate int) error {
+	return c.send(SetSampleRate, uint32(sampleRate))
+}
+
+// SetFrequencyCorrection of the remote dongle in ppm.
+func (c *Client) SetFrequencyCorrection(ppm int) error {
+	return c.send(SetFrequencyCorrection, uint32(int32(ppm)))
+}
+
+// SetManualGain switches the tuner gain mode of the remote dongle between manual and automatic.
+func (c *Client) SetManualGain(manual bool) error {
+	return c.send(SetGainMode, boolParam(manual))
+}
+
+// SetGain of the remote tuner in tenths of a dB. This is only effective in manual gain mode.
+func (c *Client) SetGain(tenthDB int) error {
+	return c.send(SetGain, uint32(int32(tenthDB)))
+}
+
+// SetAGC switches the AGC of the RTL2832 on the remote dongle on or off.
+func (c *Client) SetAGC(on bool) error {
+	return c.send(SetAGCMode, boolParam(on))
+}
+
+// SetTunerSettings applies the given settings to the remote tuner. The tuner bandwidth cannot be set through rtl_tcp.
+func (c *Client) SetTunerSettings(settings core.TunerSettings) error {
+	err := c.SetManualGain(settings.ManualGain)
+	if err == nil && settings.ManualGain {
+		err = c.SetGain(int(math.Round(float64(settings.Gain) * 10)))
+	}
+	if err == nil {
+		err = c.SetAGC(settings.AGC)
+	}
+	if err == nil && settings.Bandwidth != 0 {
+		log.Print("rtl_tcp does not support to set the tuner bandwidth")
+	}
+	return err
+}
+
+func boolParam(b bool) uint32 {
+	if b {
+		return 1
+	}
+	return 0
+}
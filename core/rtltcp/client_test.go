@@ -0,0 +1,110 @@
+package rtltcp
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ftl/panacotta/core"
+)
+
+type receivedCommand struct {
+	command Command
+	param   uint32
+}
+
+// standIn is a minimal rtl_tcp server that records the received commands and sends the given data.
+func standIn(t *testing.T, data []byte) (string, <-chan receivedCommand) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	commands := make(chan receivedCommand, 10)
+	go func() {
+		defer listener.Close()
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		writeDongleInfo(conn, DongleInfo{TunerType: TunerR820T, GainCount: 29})
+		go conn.Write(data)
+		for {
+			command, param, err := readCommand(conn)
+			if err != nil {
+				close(commands)
+				return
+			}
+			commands <- receivedCommand{command, param}
+		}
+	}()
+
+	return listener.Addr().String(), commands
+}
+
+func TestDialConfiguresDongle(t *testing.T) {
+	address, commands := standIn(t, nil)
+
+	client, err := Dial(address, 67449000, 1800000, 4, -3, core.TunerSettings{})
+	require.NoError(t, err)
+
+	assert.Equal(t, DongleInfo{TunerType: TunerR820T, GainCount: 29}, client.DongleInfo())
+	assert.Equal(t, receivedCommand{SetSampleRate, 1800000}, <-commands)
+	assert.Equal(t, receivedCommand{SetFrequency, 67449000}, <-commands)
+	assert.Equal(t, receivedCommand{SetFrequencyCorrection, 0xFFFFFFFD}, <-commands)
+	assert.Equal(t, receivedCommand{SetGainMode, 0}, <-commands)
+	assert.Equal(t, receivedCommand{SetAGCMode, 0}, <-commands)
+
+	require.NoError(t, client.SetTunerSettings(core.TunerSettings{ManualGain: true, Gain: 29.7, AGC: true}))
+	assert.Equal(t, receivedCommand{SetGainMode, 1}, <-commands)
+	assert.Equal(t, receivedCommand{SetGain, 297}, <-commands)
+	assert.Equal(t, receivedCommand{SetAGCMode, 1}, <-commands)
+
+	client.Close()
+	_, open := <-commands
+	assert.False(t, open, "connection should be closed")
+}
+
+func TestClientStreamsSamples(t *testing.T) {
+	address, _ := standIn(t, []byte{0, 255, 255, 0, 128, 127, 0, 0})
+
+	client, err := Dial(address, 67449000, 1800000, 2, 0, core.TunerSettings{})
+	require.NoError(t, err)
+	defer client.Close()
+
+	expected := [][]complex128{
+		{complex(-1, 1), complex(1, -1)},
+		{complex(0.5/127.5, -0.5/127.5), complex(-1, -1)},
+	}
+	expectedClipping := []float64{1, 0.5}
+	for i, block := range expected {
+		select {
+		case samples := <-client.Samples():
+			assert.Equal(t, block, samples)
+			assert.Equal(t, expectedClipping[i], client.Clipping(samples))
+			client.Release(samples)
+		case <-time.After(100 * time.Millisecond):
+			assert.Fail(t, "missing samples")
+		}
+	}
+}
+
+func TestDialRejectsInvalidHeader(t *testing.T) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("HTTP/1.1 400"))
+	}()
+
+	_, err = Dial(listener.Addr().String(), 67449000, 1800000, 2, 0, core.TunerSettings{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,115 @@
+// Package rtltcp implements the network protocol of rtl_tcp, the TCP server that comes with librtlsdr.
+//
+// After connecting, the server sends a 12 byte dongle info header ("RTL0", tuner type, number of gain values). Then it
+// streams interleaved unsigned 8-bit IQ samples. The client controls the dongle with 5 byte commands (command byte,
+// 32-bit big endian parameter).
+package rtltcp
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	dongleMagic      = "RTL0"
+	dongleInfoLength = 12
+	commandLength    = 5
+)
+
+// TunerType of the dongle.
+type TunerType uint32
+
+// All tuner types known by librtlsdr.
+const (
+	TunerUnknown TunerType = iota
+	TunerE4000
+	TunerFC0012
+	TunerFC0013
+	TunerFC2580
+	TunerR820T
+	TunerR828D
+)
+
+var tunerTypeNames = map[string]TunerType{
+	"RTLSDR_TUNER_E4000":  TunerE4000,
+	"RTLSDR_TUNER_FC0012": TunerFC0012,
+	"RTLSDR_TUNER_FC0013": TunerFC0013,
+	"RTLSDR_TUNER_FC2580": TunerFC2580,
+	"RTLSDR_TUNER_R820T":  TunerR820T,
+	"RTLSDR_TUNER_R828D":  TunerR828D,
+}
+
+// TunerTypeByName returns the tuner type with the given librtlsdr name (e.g. RTLSDR_TUNER_R820T).
+func TunerTypeByName(name string) TunerType {
+	return tunerTypeNames[name]
+}
+
+// DongleInfo is the header that the server sends to each client after the connection is established.
+type DongleInfo struct {
+	TunerType TunerType
+	GainCount int
+}
+
+func readDongleInfo(in io.Reader) (DongleInfo, error) {
+	header := make([]byte, dongleInfoLength)
+	_, err := io.ReadFull(in, header)
+	if err != nil {
+		return DongleInfo{}, errors.Wrap(err, "cannot read dongle info")
+	}
+	if string(header[0:4]) != dongleMagic {
+		return DongleInfo{}, errors.Errorf("invalid dongle info %q", header[0:4])
+	}
+	return DongleInfo{
+		TunerType: TunerType(binary.BigEndian.Uint32(header[4:])),
+		GainCount: int(binary.BigEndian.Uint32(header[8:])),
+	}, nil
+}
+
+func writeDongleInfo(out io.Writer, info DongleInfo) error {
+	header := make([]byte, dongleInfoLength)
+	copy(header, dongleMagic)
+	binary.BigEndian.PutUint32(header[4:], uint32(info.TunerType))
+	binary.BigEndian.PutUint32(header[8:], uint32(info.GainCount))
+	_, err := out.Write(header)
+	return err
+}
+
+// Command of the rtl_tcp protocol.
+type Command byte
+
+// All commands of the rtl_tcp protocol.
+const (
+	SetFrequency           Command = 0x01
+	SetSampleRate          Command = 0x02
+	SetGainMode            Command = 0x03
+	SetGain                Command = 0x04
+	SetFrequencyCorrection Command = 0x05
+	SetIFGain              Command = 0x06
+	SetTestMode            Command = 0x07
+	SetAGCMode             Command = 0x08
+	SetDirectSampling      Command = 0x09
+	SetOffsetTuning        Command = 0x0a
+	SetRTLXtal             Command = 0x0b
+	SetTunerXtal           Command = 0x0c
+	SetGainByIndex         Command = 0x0d
+	SetBiasTee             Command = 0x0e
+)
+
+func writeCommand(out io.Writer, command Command, param uint32) error {
+	buffer := make([]byte, commandLength)
+	buffer[0] = byte(command)
+	binary.BigEndian.PutUint32(buffer[1:], param)
+	_, err := out.Write(buffer)
+	return err
+}
+
+func readCommand(in io.Reader) (Command, uint32, error) {
+	buffer := make([]byte, commandLength)
+	_, err := io.ReadFull(in, buffer)
+	if err != nil {
+		return 0, 0, err
+	}
+	return Command(buffer[0]), binary.BigEndian.Uint32(buffer[1:]), nil
+}
@@ -0,0 +1,148 @@
+package rtltcp
+
+import (
+	"log"
+	"net"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Listen returns a new rtl_tcp server that listens at the given address. The server shares the raw samples of a
+// dongle with other applications. The dongle is owned by panacotta, therefore all commands sent by the clients are
+// refused.
+func Listen(address string, info DongleInfo) (*Server, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot open rtl_tcp server")
+	}
+	log.Printf("rtl_tcp server listening at %s", listener.Addr())
+
+	result := Server{
+		listener:    listener,
+		info:        info,
+		clients:     make(map[*serverClient]bool),
+		clientsLock: new(sync.Mutex),
+	}
+	return &result, nil
+}
+
+// Server shares raw samples using the rtl_tcp protocol.
+type Server struct {
+	listener    net.Listener
+	info        DongleInfo
+	clients     map[*serverClient]bool
+	clientsLock *sync.Mutex
+}
+
+type serverClient struct {
+	conn net.Conn
+	data chan []byte
+}
+
+// Addr returns the network address of the server.
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Run the server until the given stop channel is closed.
+func (s *Server) Run(stop chan struct{}) {
+	go func() {
+		<-stop
+		s.listener.Close()
+	}()
+	defer s.shutdown()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			select {
+			case <-stop:
+			default:
+				log.Printf("rtl_tcp server cannot accept connections: %v", err)
+			}
+			return
+		}
+		s.serve(conn)
+	}
+}
+
+func (s *Server) shutdown() {
+	s.clientsLock.Lock()
+	defer s.clientsLock.Unlock()
+	for client := range s.clients {
+		client.conn.Close()
+	}
+	log.Print("rtl_tcp server shutdown")
+}
+
+func (s *Server) serve(conn net.Conn) {
+	log.Printf("rtl_tcp client %s connected", conn.RemoteAddr())
+	client := &serverClient{
+		conn: conn,
+		data: make(chan []byte, 16),
+	}
+
+	s.clientsLock.Lock()
+	s.clients[client] = true
+	s.clientsLock.Unlock()
+
+	go s.refuseCommands(client)
+	go s.writeData(client)
+}
+
+func (s *Server) remove(client *serverClient) {
+	s.clientsLock.Lock()
+	defer s.clientsLock.Unlock()
+	if !s.clients[client] {
+		return
+	}
+	delete(s.clients, client)
+	close(client.data)
+	client.conn.Close()
+	log.Printf("rtl_tcp client %s disconnected", client.conn.RemoteAddr())
+}
+
+func (s *Server) refuseCommands(client *serverClient) {
+	defer s.remove(client)
+	for {
+		command, param, err := readCommand(client.conn)
+		if err != nil {
+			return
+		}
+		log.Printf("rtl_tcp client %s: command %d (%d) refused, the dongle is controlled by panacotta", client.conn.RemoteAddr(), command, param)
+	}
+}
+
+func (s *Server) writeData(client *serverClient) {
+	defer s.remove(client)
+	err := writeDongleInfo(client.conn, s.info)
+	if err != nil {
+		return
+	}
+	for data := range client.data {
+		_, err := client.conn.Write(data)
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Write the given raw data to all connected clients. The data is copied, the caller may reuse the given slice.
+func (s *Server) Write(data []byte) {
+	s.clientsLock.Lock()
+	defer s.clientsLock.Unlock()
+	if len(s.clients) == 0 {
+		return
+	}
+
+	block := make([]byte, len(data))
+	copy(block, data)
+	for client := range s.clients {
+		select {
+		case client.data <- block:
+		default:
+			log.Printf("rtl_tcp client %s is too slow, dropping data", client.conn.RemoteAddr())
+		}
+	}
+}
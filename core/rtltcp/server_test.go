@@ -0,0 +1,70 @@
+package rtltcp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ftl/panacotta/core"
+)
+
+func TestServerSharesRawData(t *testing.T) {
+	stop := make(chan struct{})
+	defer close(stop)
+	server, err := Listen("localhost:0", DongleInfo{TunerType: TunerR820T, GainCount: 29})
+	require.NoError(t, err)
+	go server.Run(stop)
+
+	client, err := Dial(server.Addr().String(), 100000000, 2400000, 2, 0, core.TunerSettings{})
+	require.NoError(t, err)
+	defer client.Close()
+	assert.Equal(t, DongleInfo{TunerType: TunerR820T, GainCount: 29}, client.DongleInfo())
+
+	waitForClients(t, server, 1)
+	data := []byte{255, 255, 255, 0}
+	server.Write(data)
+	data[0] = 0
+
+	select {
+	case samples := <-client.Samples():
+		assert.Equal(t, []complex128{complex(1, 1), complex(1, -1)}, samples)
+	case <-time.After(100 * time.Millisecond):
+		assert.Fail(t, "missing samples")
+	}
+}
+
+func TestServerRemovesDisconnectedClients(t *testing.T) {
+	stop := make(chan struct{})
+	defer close(stop)
+	server, err := Listen("localhost:0", DongleInfo{})
+	require.NoError(t, err)
+	go server.Run(stop)
+
+	client, err := Dial(server.Addr().String(), 100000000, 2400000, 2, 0, core.TunerSettings{})
+	require.NoError(t, err)
+	waitForClients(t, server, 1)
+
+	client.Close()
+	waitForClients(t, server, 0)
+}
+
+func waitForClients(t *testing.T, server *Server, count int) {
+	t.Helper()
+	timeout := time.After(time.Second)
+	for {
+		server.clientsLock.Lock()
+		actual := len(server.clients)
+		server.clientsLock.Unlock()
+		if actual == count {
+			return
+		}
+		select {
+		case <-timeout:
+			assert.Failf(t, "wrong number of clients", "expected %d, got %d", count, actual)
+			return
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
@@ -29,6 +29,7 @@ func Open(address string) (*VFO, error) {
 		command:         make(chan command, 1),
 		stateLock:       new(sync.RWMutex),
 		data:            make(chan core.VFO, 1),
+		splitSupported:  true,
 	}
 
 	err := result.reconnect()
@@ -50,6 +51,7 @@ type VFO struct {
 	command         chan command
 	state           core.VFO
 	stateLock       *sync.RWMutex
+	splitSupported  bool // false after the rig answered a split request with an error
 
 	data chan core.VFO
 }
@@ -59,11 +61,16 @@ func (v *VFO) Run(stop chan struct{}) {
 	go func() {
 		defer v.shutdown()
 
+		splitPolling := time.NewTicker(v.pollingInterval)
+		defer splitPolling.Stop()
+
 		for {
 			var err error
 			select {
 			case cmd := <-v.command:
 				err = cmd()
+			case <-splitPolling.C:
+				err = v.pollSplit()
 			case <-stop:
 				return
 			}
@@ -89,6 +96,7 @@ func (v *VFO) reconnect() error {
 		protocol.PollCommandFunc(v.handleNameResponse, "v"),
 		protocol.PollCommandFunc(v.handleFrequencyResponse, "f"),
 		protocol.PollCommandFunc(v.handleModeResponse, "m"),
+		protocol.PollCommandFunc(v.handlePTTResponse, "t"),
 	)
 	v.trx.WhenDone(func() {
 		out.Close()
@@ -171,6 +179,128 @@ func setName(name string) func(*core.VFO) {
 	}
 }
 
+// pollSplit polls the split state and the TX VFO. These requests are not part of the transceiver's polling, because
+// rigs without split support answer them with an RPRT error on every poll. After the first error, the requests are
+// dropped for the rest of the session.
+func (v *VFO) pollSplit() error {
+	if !v.splitSupported {
+		return nil
+	}
+
+	requests := []protocol.PollRequest{
+		protocol.PollCommandFunc(v.handleSplitResponse, "s"),
+		protocol.PollCommandFunc(v.handleTXFrequencyResponse, "i"),
+		protocol.PollCommandFunc(v.handleTXModeResponse, "x"),
+	}
+	for _, r := range requests {
+		ctx, cancel := context.WithTimeout(context.Background(), v.trxTimeout)
+		request := protocol.Request{Command: r.Command, Args: r.Args}
+		response, err := v.trx.Send(ctx, request)
+		cancel()
+		if err != nil {
+			return errors.Wrapf(err, "sending poll request %s failed", r.Command.Long)
+		}
+		if response.Result != "0" {
+			v.splitSupported = false
+			return errors.Errorf("%s is not supported (RPRT %s), no longer polling the split state", r.Command.Long, response.Result)
+		}
+
+		err = r.Handler.Handle(request, response)
+		if err != nil {
+			return errors.Wrapf(err, "receiving poll response %s failed", r.Command.Long)
+		}
+	}
+	return nil
+}
+
+func (v *VFO) handleSplitResponse(_ protocol.Request, response protocol.Response) error {
+	if len(response.Data) < 2 {
+		log.Printf("empty response %v", response)
+		return errors.New("empty response")
+	}
+
+	split := response.Data[0] == "1"
+	name := response.Data[1]
+	if strings.HasPrefix(name, "VFO") && len(name) > 3 {
+		name = name[3:]
+	}
+	v.updateState(setSplit(split, name))
+
+	return nil
+}
+
+func setSplit(split bool, txName string) func(*core.VFO) {
+	return func(state *core.VFO) {
+		state.Split = split
+		state.TX.Name = txName
+	}
+}
+
+func (v *VFO) handleTXFrequencyResponse(_ protocol.Request, response protocol.Response) error {
+	if len(response.Data) < 1 {
+		log.Printf("empty response %v", response)
+		return errors.New("empty response")
+	}
+
+	f, err := hamlibToF(response.Data[0])
+	if err != nil {
+		log.Printf("wrong frequency format %s: %v", response.Data[0], err)
+		return err
+	}
+
+	v.updateState(setTXFrequency(f))
+
+	return nil
+}
+
+func setTXFrequency(f core.Frequency) func(*core.VFO) {
+	return func(state *core.VFO) {
+		state.TX.Frequency = f
+	}
+}
+
+func (v *VFO) handleTXModeResponse(_ protocol.Request, response protocol.Response) error {
+	if len(response.Data) < 2 {
+		log.Printf("empty response %v", response)
+		return errors.New("empty response")
+	}
+
+	mode := response.Data[0]
+
+	bandwidth, err := hamlibToF(response.Data[1])
+	if err != nil {
+		log.Printf("wrong frequency format %s: %v", response.Data[0], err)
+		return err
+	}
+
+	v.updateState(setTXMode(mode, bandwidth))
+	return nil
+}
+
+func setTXMode(mode string, bandwidth core.Frequency) func(*core.VFO) {
+	return func(state *core.VFO) {
+		state.TX.Mode = mode
+		state.TX.FilterWidth = bandwidth
+	}
+}
+
+func (v *VFO) handlePTTResponse(_ protocol.Request, response protocol.Response) error {
+	if len(response.Data) < 1 {
+		log.Printf("empty response %v", response)
+		return errors.New("empty response")
+	}
+
+	v.updateState(setPTT(response.Data[0] != "0"))
+
+	return nil
+}
+
+func setPTT(ptt bool) func(*core.VFO) {
+	return func(state *core.VFO) {
+		state.PTT = ptt
+	}
+}
+
 func (v *VFO) updateState(updater func(*core.VFO)) {
 	v.stateLock.Lock()
 	defer v.stateLock.Unlock()
@@ -198,6 +328,32 @@ func (v *VFO) sendFrequency(f core.Frequency) error {
 	return nil
 }
 
+func (v *VFO) sendTXFrequency(f core.Frequency) error {
+	ctx, cancel := context.WithTimeout(context.Background(), v.trxTimeout)
+	defer cancel()
+	request := protocol.Request{Command: protocol.ShortCommand("I"), Args: []string{fToHamlib(f)}}
+	_, err := v.trx.Send(ctx, request)
+	if err != nil {
+		log.Print("Sending TX frequency failed: ", err)
+		return err
+	}
+
+	v.updateState(setTXFrequency(f))
+	return nil
+}
+
+func (v *VFO) sendMode(mode string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), v.trxTimeout)
+	defer cancel()
+	request := protocol.Request{Command: protocol.ShortCommand("M"), Args: []string{mode, "0"}}
+	_, err := v.trx.Send(ctx, request)
+	if err != nil {
+		log.Print("Sending mode failed: ", err)
+		return err
+	}
+	return nil
+}
+
 // Data of this VFO.
 func (v *VFO) Data() <-chan core.VFO {
 	return v.data
@@ -226,6 +382,20 @@ func (v *VFO) TuneBy(Δf core.Frequency) {
 	})
 }
 
+// SetTXFrequency sets the frequency of the TX VFO that is used in split operation.
+func (v *VFO) SetTXFrequency(f core.Frequency) {
+	v.q(func() error {
+		return v.sendTXFrequency(f)
+	})
+}
+
+// SetMode of the VFO. The bandwidth is set to the default of the given mode.
+func (v *VFO) SetMode(mode string) {
+	v.q(func() error {
+		return v.sendMode(mode)
+	})
+}
+
 // CurrentFrequency returns the current frequency of the VFO.
 func (v *VFO) CurrentFrequency() core.Frequency {
 	v.stateLock.RLock()
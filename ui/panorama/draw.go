@@ -3,6 +3,7 @@ package panorama
 import (
 	"fmt"
 	"math"
+	"strings"
 
 	"github.com/gotk3/gotk3/cairo"
 	"github.com/gotk3/gotk3/gtk"
@@ -49,6 +50,8 @@ type geometry struct {
 	fft            rect
 	vfo            rect
 	peaks          []rect
+	spots          []rect
+	skimmerSpots   []rect
 	waterfall      rect
 }
 
@@ -57,12 +60,14 @@ var dim = struct {
 	modeIndicatorHeight    float64
 	frequencyScaleFontSize float64
 	dbScaleFontSize        float64
+	fftSettingsFontSize    float64
 	fftWaterfallRatio      float64
 }{
 	spacing:                2.0,
-	modeIndicatorHeight:    5.0,
+	modeIndicatorHeight:    8.0,
 	frequencyScaleFontSize: 10.0,
 	dbScaleFontSize:        10.0,
+	fftSettingsFontSize:    10.0,
 	fftWaterfallRatio:      0.5,
 }
 
@@ -98,8 +103,13 @@ func (v *View) onDraw(da *gtk.DrawingArea, cr *cairo.Context) {
 	g.frequencyScale = drawFrequencyScale(cr, g, data)
 	g.modeIndicator = drawModeIndicator(cr, g, data)
 	g.fft = drawFFT(cr, g, data)
+	drawFFTSettings(cr, g, data)
+	drawOverload(cr, g, data)
+	drawInputState(cr, g, data)
 	g.waterfall = v.drawWaterfall(cr, g, data)
 	g.peaks = drawPeaks(cr, g, data)
+	g.skimmerSpots = drawSkimmerSpots(cr, g, data)
+	g.spots = drawSpots(cr, g, data)
 	g.vfo = drawVFO(cr, g, data)
 
 	v.geometry = g
@@ -245,9 +255,42 @@ func drawFrequencyScale(cr *cairo.Context, g geometry, data core.Panorama) rect
 		cr.ShowText(freqText)
 	}
 
+	cr.SetDash([]float64{}, 0)
+	markerSize := r.height() / 2
+	for _, bookmark := range data.Bookmarks {
+		x := r.toX(bookmark.X)
+		red, green, blue := bookmarkColor(bookmark.Color)
+
+		labelExtents := cr.TextExtents(bookmark.Label)
+		cr.SetSourceRGBA(0, 0, 0, 0.7)
+		cr.Rectangle(x, r.top, labelExtents.Width+markerSize/2+2*dim.spacing, r.height())
+		cr.Fill()
+
+		cr.SetSourceRGB(red, green, blue)
+		cr.MoveTo(x-markerSize/2, r.bottom-markerSize)
+		cr.LineTo(x+markerSize/2, r.bottom-markerSize)
+		cr.LineTo(x, r.bottom)
+		cr.ClosePath()
+		cr.Fill()
+
+		cr.MoveTo(x+markerSize/2+dim.spacing, r.bottom-dim.spacing)
+		cr.ShowText(bookmark.Label)
+	}
+
 	return r
 }
 
+// bookmarkColor returns the colour of a bookmark. The given colour is a hex string like #ff8000, the default colour is
+// used if it cannot be parsed.
+func bookmarkColor(color string) (red, green, blue float64) {
+	var r, g, b uint8
+	_, err := fmt.Sscanf(color, "#%02x%02x%02x", &r, &g, &b)
+	if err != nil {
+		return 1, 0.5, 1
+	}
+	return float64(r) / 255, float64(g) / 255, float64(b) / 255
+}
+
 func drawModeIndicator(cr *cairo.Context, g geometry, data core.Panorama) rect {
 	cr.Save()
 	defer cr.Restore()
@@ -260,6 +303,7 @@ func drawModeIndicator(cr *cairo.Context, g geometry, data core.Panorama) rect {
 	}
 
 	cr.SetLineWidth(1.0)
+	cr.SetFontSize(dim.modeIndicatorHeight - 1)
 
 	for _, portion := range data.Band.Portions {
 		startX := r.toX(core.ToFrequencyFrct(portion.From, data.FrequencyRange))
@@ -287,6 +331,15 @@ func drawModeIndicator(cr *cairo.Context, g geometry, data core.Panorama) rect {
 
 		cr.Rectangle(startX, r.top+yOffset, endX-startX, dim.modeIndicatorHeight)
 		cr.Fill()
+
+		label := string(portion.Mode)
+		extents := cr.TextExtents(label)
+		if extents.Width+2*dim.spacing > endX-startX {
+			continue
+		}
+		cr.SetSourceRGB(0.8, 0.8, 0.8)
+		cr.MoveTo(startX+(endX-startX-extents.Width)/2, r.top+yOffset+dim.modeIndicatorHeight-1)
+		cr.ShowText(label)
 	}
 
 	return r
@@ -320,9 +373,92 @@ func drawFFT(cr *cairo.Context, g geometry, data core.Panorama) rect {
 	}
 	cr.Stroke()
 
+	if len(data.HoldSpectrum) > 0 {
+		cr.SetSourceRGB(1, 0.8, 0)
+		cr.MoveTo(r.toX(data.HoldSpectrum[0].X), r.toY(data.HoldSpectrum[0].Y))
+		for _, p := range data.HoldSpectrum {
+			cr.LineTo(r.toX(p.X), r.toY(p.Y))
+		}
+		cr.Stroke()
+	}
+
+	cr.SetSourceRGBA(0.3, 0.6, 1, 0.8)
+	cr.SetLineWidth(1.0)
+	cr.SetDash([]float64{4, 2}, 0)
+	y := r.toY(data.NoiseFloorLevel)
+	cr.MoveTo(r.left, y)
+	cr.LineTo(r.right, y)
+	cr.Stroke()
+
 	return r
 }
 
+func drawFFTSettings(cr *cairo.Context, g geometry, data core.Panorama) {
+	cr.Save()
+	defer cr.Restore()
+
+	padding := 4.0
+	cr.SetFontSize(dim.fftSettingsFontSize)
+	text := fmt.Sprintf("window: %s | averaging: %s", data.FFTWindow, data.Averaging)
+	extents := cr.TextExtents(text)
+
+	cr.SetSourceRGB(0.8, 0.8, 0.8)
+	cr.MoveTo(g.fft.left+padding, g.fft.top+padding+extents.Height)
+	cr.ShowText(text)
+}
+
+func drawOverload(cr *cairo.Context, g geometry, data core.Panorama) {
+	if !data.Overload {
+		return
+	}
+
+	cr.Save()
+	defer cr.Restore()
+
+	padding := 4.0
+	cr.SetFontSize(15.0)
+	text := "OVERLOAD"
+	extents := cr.TextExtents(text)
+	x := g.fft.right - extents.Width - 3*padding
+	y := g.fft.top + padding
+
+	cr.SetSourceRGB(1, 0, 0)
+	cr.Rectangle(x, y, extents.Width+2*padding, extents.Height+2*padding)
+	cr.Fill()
+
+	cr.SetSourceRGB(1, 1, 1)
+	cr.MoveTo(x+padding, y+padding+extents.Height)
+	cr.ShowText(text)
+}
+
+func drawInputState(cr *cairo.Context, g geometry, data core.Panorama) {
+	if data.InputState == core.InputRunning {
+		return
+	}
+
+	cr.Save()
+	defer cr.Restore()
+
+	cr.SetSourceRGBA(0, 0, 0, 0.6)
+	cr.Rectangle(g.fft.left, g.fft.top, g.fft.width(), g.fft.height())
+	cr.Fill()
+
+	padding := 8.0
+	cr.SetFontSize(30.0)
+	text := strings.ToUpper(data.InputState.String())
+	extents := cr.TextExtents(text)
+	x := g.fft.left + (g.fft.width()-extents.Width)/2
+	y := g.fft.top + (g.fft.height()-extents.Height)/2
+
+	cr.SetSourceRGB(1, 0.5, 0)
+	cr.Rectangle(x-padding, y-padding, extents.Width+2*padding, extents.Height+2*padding)
+	cr.Fill()
+
+	cr.SetSourceRGB(0, 0, 0)
+	cr.MoveTo(x, y+extents.Height)
+	cr.ShowText(text)
+}
+
 func drawVFO(cr *cairo.Context, g geometry, data core.Panorama) rect {
 	cr.Save()
 	defer cr.Restore()
@@ -334,6 +470,10 @@ func drawVFO(cr *cairo.Context, g geometry, data core.Panorama) rect {
 
 	freqX := g.fft.toX(data.VFOLine)
 	padding := 4.0
+	red, green, blue := 0.6, 0.9, 1.0
+	if data.VFOOutOfSegment {
+		red, green, blue = 1.0, 0.4, 0.2
+	}
 	filterX := g.fft.toX(data.VFOFilterFrom)
 	filterWidth := g.fft.toX(data.VFOFilterTo) - g.fft.toX(data.VFOFilterFrom)
 	r.left = filterX
@@ -341,21 +481,24 @@ func drawVFO(cr *cairo.Context, g geometry, data core.Panorama) rect {
 	mouseOver := r.contains(g.mouse)
 
 	if mouseOver {
-		cr.SetSourceRGBA(0.6, 0.9, 1.0, 0.5)
+		cr.SetSourceRGBA(red, green, blue, 0.5)
 	} else {
-		cr.SetSourceRGBA(0.6, 0.9, 1.0, 0.2)
+		cr.SetSourceRGBA(red, green, blue, 0.2)
 	}
 	cr.Rectangle(filterX, r.top, filterWidth, r.height())
 	cr.Fill()
 
 	cr.SetLineWidth(1.5)
-	cr.SetSourceRGB(0.6, 0.9, 1.0)
+	cr.SetSourceRGB(red, green, blue)
 	cr.MoveTo(freqX, r.top)
 	cr.LineTo(freqX, r.bottom)
 	cr.Stroke()
 
 	cr.SetFontSize(15.0)
 	freqText := fmt.Sprintf("%s:%.2fkHz", data.VFO.Name, data.VFO.Frequency/1000)
+	if data.VFO.PTT {
+		freqText += " TX"
+	}
 	freqExtents := cr.TextExtents(freqText)
 	leftSide := freqX+padding+freqExtents.Width < g.fft.right
 	if leftSide {
@@ -375,9 +518,61 @@ func drawVFO(cr *cairo.Context, g geometry, data core.Panorama) rect {
 	}
 	cr.ShowText(sMeterText)
 
+	drawTXVFO(cr, g, data, freqExtents.Height+sMeterExtents.Height+3*padding)
+
 	return r
 }
 
+// drawTXVFO draws the TX VFO for split operation below the texts of the VFO.
+func drawTXVFO(cr *cairo.Context, g geometry, data core.Panorama, textOffset float64) {
+	if data.VFO.TX.Frequency == 0 {
+		return
+	}
+
+	top := g.fft.top
+	bottom := g.waterfall.bottom
+	freqX := g.fft.toX(data.TXVFOLine)
+	filterX := g.fft.toX(data.TXVFOFilterFrom)
+	filterWidth := g.fft.toX(data.TXVFOFilterTo) - filterX
+	if freqX < g.fft.left || freqX > g.fft.right {
+		return
+	}
+	padding := 4.0
+
+	if data.VFO.Split {
+		cr.SetSourceRGBA(1.0, 0.6, 0.9, 0.2)
+		cr.Rectangle(filterX, top, filterWidth, bottom-top)
+		cr.Fill()
+	} else {
+		cr.SetDash([]float64{4, 4}, 0)
+	}
+
+	cr.SetLineWidth(1.5)
+	cr.SetSourceRGB(1.0, 0.6, 0.9)
+	cr.MoveTo(freqX, top)
+	cr.LineTo(freqX, bottom)
+	cr.Stroke()
+	cr.SetDash([]float64{}, 0)
+
+	name := data.VFO.TX.Name
+	if name == "" {
+		name = "TX"
+	}
+	freqText := fmt.Sprintf("%s:%.2fkHz", name, data.VFO.TX.Frequency/1000)
+	if data.VFO.Split {
+		freqText += " SPLIT"
+	}
+	cr.SetFontSize(12.0)
+	freqExtents := cr.TextExtents(freqText)
+	y := top + textOffset + freqExtents.Height + padding
+	if freqX+padding+freqExtents.Width < g.fft.right {
+		cr.MoveTo(freqX+padding, y)
+	} else {
+		cr.MoveTo(freqX-padding-freqExtents.Width, y)
+	}
+	cr.ShowText(freqText)
+}
+
 func drawPeaks(cr *cairo.Context, g geometry, data core.Panorama) []rect {
 	cr.Save()
 	defer cr.Restore()
@@ -409,12 +604,23 @@ func drawPeaks(cr *cairo.Context, g geometry, data core.Panorama) []rect {
 		cr.ShowText(markText)
 
 		cr.SetFontSize(10.0)
+		if label := cwLabel(peak); label != "" {
+			labelExtents := cr.TextExtents(label)
+			cr.MoveTo(maxX-labelExtents.Width/2, markTextY-markExtents.Height-dim.spacing)
+			cr.ShowText(label)
+		}
 		freqText := fmt.Sprintf("%.2fkHz", peak.MaxFrequency/1000)
+		if peak.Class != core.SignalUnknown {
+			freqText = fmt.Sprintf("%s %s", freqText, peak.Class)
+		}
 		freqExtents := cr.TextExtents(freqText)
-		sMeterText := fmt.Sprintf("%s", core.SUnit(peak.ValueDB).String())
+		sMeterText := fmt.Sprintf("%s | SNR %.0fdB", core.SUnit(peak.ValueDB).String(), peak.SNR)
 		sMeterExtents := cr.TextExtents(sMeterText)
 
 		freqTextY := markTextY - 2*dim.spacing - markExtents.Height - sMeterExtents.Height
+		if cwLabel(peak) != "" {
+			freqTextY -= dim.spacing + sMeterExtents.Height
+		}
 		sMeterTextY := freqTextY + dim.spacing + sMeterExtents.Height
 
 		leftSide := maxX+padding+freqExtents.Width < g.fft.right
@@ -447,6 +653,152 @@ func drawPeaks(cr *cairo.Context, g geometry, data core.Panorama) []rect {
 	return result
 }
 
+func drawSpots(cr *cairo.Context, g geometry, data core.Panorama) []rect {
+	cr.Save()
+	defer cr.Restore()
+
+	const rows = 3
+	padding := 2.0
+	cr.SetFontSize(10.0)
+	rowHeight := cr.TextExtents("Hg").Height + 2*padding
+	rowEnds := make([]float64, rows)
+	for i := range rowEnds {
+		rowEnds[i] = g.fft.left
+	}
+
+	result := make([]rect, len(data.Spots))
+	for i, spot := range data.Spots {
+		x := g.fft.toX(spot.X)
+		label := spot.Call
+		if spot.Mode != "" {
+			label = fmt.Sprintf("%s %s", spot.Call, spot.Mode)
+		}
+		extents := cr.TextExtents(label)
+
+		// the spots are sorted by frequency, put each label into the lowest row where it does not overlap
+		row := 0
+		for row < rows-1 && rowEnds[row] > x {
+			row++
+		}
+		rowEnds[row] = x + extents.Width + 2*padding
+
+		r := rect{
+			left:   x,
+			top:    g.fft.bottom - float64(row+1)*rowHeight,
+			right:  x + extents.Width + 2*padding,
+			bottom: g.fft.bottom - float64(row)*rowHeight,
+		}
+		mouseOver := r.contains(g.mouse)
+
+		cr.SetLineWidth(1.0)
+		cr.SetSourceRGB(1, 0.8, 0.2)
+		cr.MoveTo(x, r.top)
+		cr.LineTo(x, g.fft.bottom)
+		cr.Stroke()
+
+		if mouseOver {
+			cr.SetSourceRGBA(1, 0.8, 0.2, 0.6)
+		} else {
+			cr.SetSourceRGBA(0, 0, 0, 0.6)
+		}
+		cr.Rectangle(r.left, r.top, r.width(), r.height())
+		cr.Fill()
+
+		cr.SetSourceRGB(1, 0.8, 0.2)
+		if mouseOver {
+			cr.SetSourceRGB(0, 0, 0)
+		}
+		cr.MoveTo(x+padding, r.bottom-padding)
+		cr.ShowText(label)
+
+		result[i] = r
+	}
+
+	return result
+}
+
+func drawSkimmerSpots(cr *cairo.Context, g geometry, data core.Panorama) []rect {
+	cr.Save()
+	defer cr.Restore()
+
+	markerWidth := 3.0
+	markerHeight := 8.0
+	padding := 4.0
+	cr.SetFontSize(10.0)
+
+	result := make([]rect, len(data.SkimmerSpots))
+	var hoverText string
+	var hoverX float64
+	for i, spot := range data.SkimmerSpots {
+		x := g.fft.toX(spot.X)
+		r := rect{
+			left:   x - markerWidth/2 - 1,
+			top:    g.fft.bottom - markerHeight,
+			right:  x + markerWidth/2 + 1,
+			bottom: g.waterfall.top + markerHeight,
+		}
+
+		red, green, blue := snrColor(spot.SNR)
+		cr.SetSourceRGB(red, green, blue)
+		cr.Rectangle(x-markerWidth/2, r.top, markerWidth, r.height())
+		cr.Fill()
+
+		if r.contains(g.mouse) {
+			hoverText = fmt.Sprintf("%s %dWPM %.0fdB", spot.Call, spot.WPM, spot.SNR)
+			if spot.Spotters > 1 {
+				hoverText = fmt.Sprintf("%s (%d)", hoverText, spot.Spotters)
+			}
+			hoverX = x
+		}
+		result[i] = r
+	}
+
+	if hoverText != "" {
+		extents := cr.TextExtents(hoverText)
+		textX := hoverX + padding
+		if textX+extents.Width > g.fft.right {
+			textX = hoverX - padding - extents.Width
+		}
+		textY := g.fft.bottom - markerHeight - padding
+		cr.SetSourceRGBA(0, 0, 0, 0.7)
+		cr.Rectangle(textX-padding/2, textY-extents.Height-padding/2, extents.Width+padding, extents.Height+padding)
+		cr.Fill()
+		cr.SetSourceRGB(1, 1, 1)
+		cr.MoveTo(textX, textY)
+		cr.ShowText(hoverText)
+	}
+
+	return result
+}
+
+// snrColor returns the colour of a skimmer spot with the given SNR, from blue for weak to red for strong signals.
+func snrColor(snr core.DB) (red, green, blue float64) {
+	switch {
+	case snr < 10:
+		return 0.3, 0.5, 1
+	case snr < 20:
+		return 0.3, 1, 0.3
+	case snr < 30:
+		return 1, 1, 0.2
+	default:
+		return 1, 0.3, 0.2
+	}
+}
+
+// cwLabel returns the callsign that was decoded from the CW signal of the given peak, or the last characters of the
+// decoded text if no callsign was found.
+func cwLabel(peak core.PeakMark) string {
+	const maxLength = 12
+	if peak.Callsign != "" {
+		return peak.Callsign
+	}
+	text := []rune(peak.CWText)
+	if len(text) > maxLength {
+		text = text[len(text)-maxLength:]
+	}
+	return string(text)
+}
+
 func (v *View) drawWaterfall(cr *cairo.Context, g geometry, data core.Panorama) rect {
 	cr.Save()
 	defer cr.Restore()
@@ -466,6 +818,16 @@ func (v *View) drawWaterfall(cr *cairo.Context, g geometry, data core.Panorama)
 		v.waterfall = make([]byte, length)
 	}
 
+	if data.Frozen {
+		// while transmitting, the waterfall stands still after a TX marker line
+		if !v.transmitting {
+			v.transmitting = true
+			v.waterfall = append(txMarkerLine(stride, bytesPerPx), v.waterfall[:length-stride]...)
+		}
+		return v.paintWaterfall(cr, r, stride)
+	}
+	v.transmitting = false
+
 	waterline := make([]byte, stride)
 	for i := range data.Waterline {
 		j := i * bytesPerPx
@@ -480,6 +842,10 @@ func (v *View) drawWaterfall(cr *cairo.Context, g geometry, data core.Panorama)
 	}
 	v.waterfall = append(waterline, v.waterfall[:length-stride]...)
 
+	return v.paintWaterfall(cr, r, stride)
+}
+
+func (v *View) paintWaterfall(cr *cairo.Context, r rect, stride int) rect {
 	imageSurface, _ := cairo.CreateImageSurfaceForData(v.waterfall, cairo.FORMAT_RGB24, int(r.width()), int(r.height()), stride)
 	defer imageSurface.Close()
 
@@ -488,3 +854,12 @@ func (v *View) drawWaterfall(cr *cairo.Context, g geometry, data core.Panorama)
 
 	return r
 }
+
+// txMarkerLine returns a red line for the waterfall that marks the start of a transmission.
+func txMarkerLine(stride int, bytesPerPx int) []byte {
+	result := make([]byte, stride)
+	for j := 0; j+2 < len(result); j += bytesPerPx {
+		result[j+2] = 255
+	}
+	return result
+}
@@ -16,9 +16,17 @@ func (v *View) connectKeyboard() {
 		gdk.KEY_Down:  v.controller.ZoomIn,
 		gdk.KEY_Left:  v.controller.TuneDown,
 		gdk.KEY_Right: v.controller.TuneUp,
+		gdk.KEY_a:     v.controller.NextAveragingMode,
+		gdk.KEY_b:     v.controller.AddBookmark,
+		gdk.KEY_B:     v.controller.DeleteBookmark,
 		gdk.KEY_d:     v.controller.ToggleSignalDetection,
+		gdk.KEY_h:     v.controller.ResetHold,
+		gdk.KEY_i:     v.controller.ToggleRecording,
+		gdk.KEY_n:     v.controller.NextBookmark,
+		gdk.KEY_p:     v.controller.PreviousBookmark,
 		gdk.KEY_r:     v.controller.ResetZoom,
 		gdk.KEY_v:     v.controller.ToggleViewMode,
+		gdk.KEY_w:     v.controller.NextFFTWindow,
 	}
 
 	v.view.SetCanFocus(true)
@@ -17,6 +17,7 @@ type mouse struct {
 	lastX, lastY   float64
 	startX, startY float64
 	button         uint
+	modifiers      uint
 	dragThreshold  float64
 }
 
@@ -76,6 +77,7 @@ func (v *View) onButtonPress(da *gtk.DrawingArea, e *gdk.Event) {
 	v.mouse.buttonPressed = true
 	v.mouse.startX, v.mouse.startY = buttonEvent.X(), buttonEvent.Y()
 	v.mouse.button = uint(buttonEvent.Button())
+	v.mouse.modifiers = buttonEvent.State()
 }
 
 func (v *View) onButtonRelease(da *gtk.DrawingArea, e *gdk.Event) {
@@ -91,6 +93,7 @@ func (v *View) onButtonRelease(da *gtk.DrawingArea, e *gdk.Event) {
 	v.mouse.doublePressed = false
 	v.mouse.startX, v.mouse.startY = 0, 0
 	v.mouse.button = 0
+	v.mouse.modifiers = 0
 }
 
 func (v *View) onClick(button uint) {
@@ -106,9 +109,26 @@ func (v *View) onClick(button uint) {
 
 func (v *View) onSingleLeftClick(x, y float64) {
 	pointer := point{x, y}
+	if v.mouse.modifiers&uint(gdk.SHIFT_MASK) != 0 {
+		// shift-click sets the TX VFO for split operation
+		if v.geometry.fft.contains(pointer) || v.geometry.waterfall.contains(pointer) {
+			v.controller.SetTXFrequency(v.deviceToFrequency(x))
+		}
+		return
+	}
+	for i, r := range v.geometry.spots {
+		if r.contains(pointer) {
+			spot := v.data.Spots[i]
+			v.controller.TuneTo(spot.Frequency)
+			if spot.Mode != "" {
+				v.controller.SetMode(spot.Mode)
+			}
+			return
+		}
+	}
 	for i, r := range v.geometry.peaks {
 		if r.contains(pointer) {
-			f := v.data.Peaks[i].MaxFrequency
+			f := v.data.Peaks[i].TuneFrequency
 			v.controller.TuneTo(f)
 			return
 		}
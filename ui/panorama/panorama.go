@@ -33,6 +33,8 @@ type Controller interface {
 	Panorama() <-chan core.Panorama
 	SetPanoramaSize(core.Px, core.Px)
 	TuneTo(core.Frequency)
+	SetMode(string)
+	SetTXFrequency(core.Frequency)
 	TuneBy(core.Frequency)
 	TuneUp()
 	TuneDown()
@@ -46,6 +48,14 @@ type Controller interface {
 	CoarserDynamicRange()
 	ShiftDynamicRange(core.Frct)
 	ShiftFrequencyRange(core.Frct)
+	ToggleRecording()
+	NextFFTWindow()
+	NextAveragingMode()
+	ResetHold()
+	AddBookmark()
+	DeleteBookmark()
+	NextBookmark()
+	PreviousBookmark()
 }
 
 // View of the FFT.
@@ -54,9 +64,10 @@ type View struct {
 	controller      Controller
 	sizeInitialized bool
 
-	data      core.Panorama
-	geometry  geometry
-	waterfall []byte
+	data         core.Panorama
+	geometry     geometry
+	waterfall    []byte
+	transmitting bool
 
 	mouse    mouse
 	keyboard keyboard